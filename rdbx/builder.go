@@ -0,0 +1,144 @@
+package rdbx
+
+import (
+	"strings"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// SelectBuilder builds a SELECT query and its arguments, for queries whose conditions depend on
+// the request, such as search endpoints. Every method returns a new builder, so a base query can
+// be shared and extended safely.
+//
+//	query, args := rdbx.Select().From("users").
+//		Where(rdbx.Eq("status", status)).
+//		OrderBy("created_at DESC").
+//		Limit(20).
+//		ToSQL()
+//
+//	err := db.Queryx(ctx, query, &users, args...)
+//
+// The values of conditions are always bound as arguments. Column, table and ORDER BY expressions
+// are written to the query as is: they must never come from user input.
+type SelectBuilder struct {
+	columns []string
+	from    string
+	where   []Cond
+	orderBy []string
+	limit   int
+	offset  int
+	dialect Dialect
+}
+
+// Select starts a query of the columns. Without columns it selects "*", which Queryx and
+// the generic query functions expand to the columns of the model.
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+// From sets the table, or any FROM expression such as a join.
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	c := b.clone()
+	c.from = table
+
+	return c
+}
+
+// Where adds conditions, which the rows must all match.
+func (b *SelectBuilder) Where(conds ...Cond) *SelectBuilder {
+	c := b.clone()
+	c.where = append(c.where, conds...)
+
+	return c
+}
+
+// OrderBy adds ORDER BY expressions, such as "created_at DESC".
+func (b *SelectBuilder) OrderBy(exprs ...string) *SelectBuilder {
+	c := b.clone()
+	c.orderBy = append(c.orderBy, exprs...)
+
+	return c
+}
+
+// Limit sets the most rows the query returns, zero meaning no limit.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	c := b.clone()
+	c.limit = n
+
+	return c
+}
+
+// Offset sets the number of rows the query skips.
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	c := b.clone()
+	c.offset = n
+
+	return c
+}
+
+// Dialect sets the dialect of the query, MySQLDialect by default. It decides the placeholders
+// and how LIMIT and OFFSET are written.
+func (b *SelectBuilder) Dialect(d Dialect) *SelectBuilder {
+	c := b.clone()
+	c.dialect = d
+
+	return c
+}
+
+// ToSQL returns the query and its arguments, which can be passed to Queryx, QueryAll or
+// QueryContext as they are.
+func (b *SelectBuilder) ToSQL() (string, []interface{}) {
+	var q strings.Builder
+
+	q.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		q.WriteString("*")
+	} else {
+		q.WriteString(strings.Join(b.columns, ", "))
+	}
+
+	if b.from != "" {
+		q.WriteString(" FROM ")
+		q.WriteString(b.from)
+	}
+
+	where, args := whereClause(b.where)
+	q.WriteString(where)
+
+	if len(b.orderBy) > 0 {
+		q.WriteString(" ORDER BY ")
+		q.WriteString(strings.Join(b.orderBy, ", "))
+	}
+
+	q.WriteString(b.dialect.limitClause(b.limit, b.offset, len(b.orderBy) > 0))
+
+	query := q.String()
+
+	// Numbered placeholders cannot be expanded afterwards, so slice arguments are expanded first.
+	// Every placeholder is written by the conditions along with its argument, the counts match.
+	if expanded, expandedArgs, err := internal.ExpandIn(query, args, b.dialect.syntax()); err == nil {
+		query, args = expanded, expandedArgs
+	}
+
+	return b.dialect.Rebind(query), args
+}
+
+// count returns the query that counts the rows of the query of the builder.
+func (b *SelectBuilder) count() *SelectBuilder {
+	c := b.clone()
+	c.columns = []string{"COUNT(*)"}
+	c.orderBy = nil
+	c.limit, c.offset = 0, 0
+
+	return c
+}
+
+// clone returns a copy of the builder that shares no slice with it.
+func (b *SelectBuilder) clone() *SelectBuilder {
+	c := *b
+	c.columns = append([]string(nil), b.columns...)
+	c.where = append([]Cond(nil), b.where...)
+	c.orderBy = append([]string(nil), b.orderBy...)
+
+	return &c
+}
@@ -0,0 +1,92 @@
+package rdbx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectBuilder_ToSQL(t *testing.T) {
+	base := Select().From("users").Where(Eq("status", "active"))
+
+	tests := []struct {
+		name     string
+		b        *SelectBuilder
+		want     string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "base",
+			b:        base,
+			want:     "SELECT * FROM users WHERE status = ?",
+			wantArgs: []interface{}{"active"},
+		},
+		{
+			name: "conditions",
+			b: base.Where(
+				Or(Like("name", "al%"), In("id", []int64{1, 2})),
+				Ne("deleted_at", nil),
+			),
+			want:     "SELECT * FROM users WHERE status = ? AND (name LIKE ? OR id IN (?, ?)) AND deleted_at IS NOT NULL",
+			wantArgs: []interface{}{"active", "al%", int64(1), int64(2)},
+		},
+		{
+			name:     "columns order and limit",
+			b:        Select("id", "name").From("users").OrderBy("name", "id DESC").Limit(10).Offset(20),
+			want:     "SELECT id, name FROM users ORDER BY name, id DESC LIMIT 10 OFFSET 20",
+			wantArgs: nil,
+		},
+		{
+			name:     "postgres",
+			b:        base.Where(In("id", []int64{1, 2}), Gt("age", 18)).Limit(5).Dialect(PostgresDialect),
+			want:     "SELECT * FROM users WHERE status = $1 AND id IN ($2, $3) AND age > $4 LIMIT 5",
+			wantArgs: []interface{}{"active", int64(1), int64(2), 18},
+		},
+		{
+			name:     "sqlserver",
+			b:        base.Offset(10).Limit(5).Dialect(SQLServerDialect),
+			want:     "SELECT * FROM users WHERE status = @p1 ORDER BY (SELECT NULL) OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY",
+			wantArgs: []interface{}{"active"},
+		},
+		{
+			name:     "offset without limit",
+			b:        base.Offset(10).Dialect(SQLiteDialect),
+			want:     "SELECT * FROM users WHERE status = ? LIMIT -1 OFFSET 10",
+			wantArgs: []interface{}{"active"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := tt.b.ToSQL()
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestSelectBuilder_Queryx(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID   int64  `column:"id"`
+		Name string `column:"name"`
+	}
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil, WithDialect(PostgresDialect))
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE id IN ($1, $2) ORDER BY id LIMIT 2").
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alie").AddRow(2, "bob"))
+
+	query, args := Select().From("users").Where(In("id", []int64{1, 2})).OrderBy("id").Limit(2).Dialect(PostgresDialect).ToSQL()
+
+	users, err := QueryAll[user](ctx, dbx, query, args...)
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "alie"}, {ID: 2, Name: "bob"}}, users)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,10 +1,41 @@
 package rdbx
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
+// ErrCacheMiss is returned by Cache.Get when the key does not exist.
+var ErrCacheMiss = errors.New("rdbx: cache miss")
+
+// Cache stores query results.
 type Cache interface {
-	Set(ctx context.Context, key string, value interface{}) error
+	// Set stores the value under the key, the key expires after ttl.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 	Append(ctx context.Context, key string, value interface{}) (int64, error)
 	Get(ctx context.Context, key string) ([]byte, error)
 	GetList(ctx context.Context, key string) ([]string, error)
+	// Tag adds the key to the set of keys under the tag key, once however often it is tagged.
+	// The set lives for at least ttl, or does not expire for a ttl of zero.
+	Tag(ctx context.Context, tagKey, key string, ttl time.Duration) error
+	// Delete removes the keys, missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteByTag removes the keys tagged with the tag key, and the tag set itself.
+	DeleteByTag(ctx context.Context, tagKey string) error
+	// DeleteByPrefix removes every key starting with the prefix.
+	DeleteByPrefix(ctx context.Context, prefix string) error
+	// TTL returns how long the key lives before it expires, zero when it does not expire,
+	// or ErrCacheMiss when the key does not exist.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Stats returns the usage statistics of the cache.
+	Stats(ctx context.Context) (CacheStats, error)
+}
+
+// CacheStats reports the usage of a cache.
+type CacheStats struct {
+	Hits      uint64 // Gets that found the key.
+	Misses    uint64 // Gets that did not find the key.
+	Evictions uint64 // Keys removed to stay within the memory bounds.
+	Bytes     int64  // Memory used by the cache.
 }
@@ -0,0 +1,60 @@
+package rdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// Compression is the algorithm that compresses the cached result sets.
+type Compression byte
+
+// Compression algorithms. The algorithm is recorded in every cache entry, so entries stay
+// readable when the configured compression changes.
+const (
+	NoCompression Compression = iota
+	GzipCompression
+	SnappyCompression
+)
+
+// compress compresses the payload. Compressing into memory does not fail.
+func (c Compression) compress(payload []byte) []byte {
+	switch c {
+	case GzipCompression:
+		buf := bytes.NewBuffer(make([]byte, 0, len(payload)/2))
+
+		w := gzip.NewWriter(buf)
+		_, _ = w.Write(payload)
+		_ = w.Close()
+
+		return buf.Bytes()
+	case SnappyCompression:
+		return snappy.Encode(nil, payload)
+	default:
+		return payload
+	}
+}
+
+// decompress decompresses a payload produced by compress.
+func (c Compression) decompress(payload []byte) ([]byte, error) {
+	switch c {
+	case NoCompression:
+		return payload, nil
+	case GzipCompression:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		defer r.Close()
+
+		return io.ReadAll(r)
+	case SnappyCompression:
+		return snappy.Decode(nil, payload)
+	default:
+		return nil, fmt.Errorf("rdbx: unsupported cache compression %d", c)
+	}
+}
@@ -0,0 +1,100 @@
+package rdbx
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// defaultCacheTTL is how long query results are cached when no TTL is configured.
+const defaultCacheTTL = 1800 * time.Second
+
+// contextKeyCacheTTL is a context key used to override the cache TTL of a query.
+type contextKeyCacheTTL struct{}
+
+// contextKeyCacheTags is a context key used to tag cached queries, and to invalidate tags on Exec.
+type contextKeyCacheTags struct{}
+
+// contextKeyCacheMode is a context key used to change how a query uses the cache.
+type contextKeyCacheMode struct{}
+
+// cacheMode controls whether a query reads from and writes to the cache.
+type cacheMode int
+
+const (
+	cacheModeDefault cacheMode = iota // Read from the cache, and cache the result on a miss.
+	cacheModeBypass                   // Neither read from nor write to the cache.
+	cacheModeRefresh                  // Skip the cached result, and cache the fresh result.
+)
+
+// WithCacheTTL returns a context that caches the results of queries run with it for ttl.
+// A ttl of zero or less disables caching, like NoCache.
+func WithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, contextKeyCacheTTL{}, ttl)
+}
+
+// NoCache returns a context whose queries neither read from nor write to the cache.
+func NoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyCacheMode{}, cacheModeBypass)
+}
+
+// RefreshCache returns a context whose queries always read from the database
+// and replace the cached result with the fresh one.
+func RefreshCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyCacheMode{}, cacheModeRefresh)
+}
+
+// WithCacheTags returns a context that adds the tags to the results cached by queries run with it,
+// and invalidates the tags when an Exec is run with it. Table names are tags already,
+// explicit tags cover what cannot be derived from the SQL, e.g. views or stored procedures.
+func WithCacheTags(ctx context.Context, tags ...string) context.Context {
+	return context.WithValue(ctx, contextKeyCacheTags{}, appendTags(cacheTagsFromContext(ctx), tags...))
+}
+
+// cacheTagsFromContext returns the tags set on the context.
+func cacheTagsFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(contextKeyCacheTags{}).([]string)
+
+	return tags
+}
+
+// appendTags appends the lowercased tags that are not in dst yet.
+func appendTags(dst []string, tags ...string) []string {
+	dst = dst[:len(dst):len(dst)]
+
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+
+		found := false
+		for _, t := range dst {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+
+		if !found && tag != "" {
+			dst = append(dst, tag)
+		}
+	}
+
+	return dst
+}
+
+// cacheModeFromContext returns the cache mode of the context.
+func cacheModeFromContext(ctx context.Context) cacheMode {
+	if m, ok := ctx.Value(contextKeyCacheMode{}).(cacheMode); ok {
+		return m
+	}
+
+	return cacheModeDefault
+}
+
+// cacheTTLFromContext returns the cache TTL of the context, or def when none is set.
+func cacheTTLFromContext(ctx context.Context, def time.Duration) time.Duration {
+	if ttl, ok := ctx.Value(contextKeyCacheTTL{}).(time.Duration); ok {
+		return ttl
+	}
+
+	return def
+}
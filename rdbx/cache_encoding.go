@@ -0,0 +1,103 @@
+package rdbx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// errCachedResultTooLarge is returned when a result set exceeds the maximum cached result size.
+var errCachedResultTooLarge = errors.New("rdbx: result set too large to cache")
+
+// resultEncoding configures how result sets are encoded in the cache.
+type resultEncoding struct {
+	codec       RowCodec
+	compression Compression
+	threshold   int // Payloads smaller than threshold are stored uncompressed.
+	maxSize     int // The largest encoded result set that is cached, zero or less means no limit.
+}
+
+// encode encodes a result set into a cache entry, compressing it when it is large enough.
+func (e *resultEncoding) encode(res *cachedResult, delta time.Duration, expiresAt time.Time) ([]byte, error) {
+	payload, err := e.codec.EncodeRows(res.columns, res.rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.fits(len(payload)) {
+		return nil, errCachedResultTooLarge
+	}
+
+	compression := e.compression
+	if len(payload) < e.threshold {
+		compression = NoCompression
+	}
+
+	return encodeEntry(cacheEntry{
+		payload:     payload,
+		compression: compression,
+		delta:       delta,
+		expiresAt:   expiresAt,
+	}), nil
+}
+
+// decode decodes a cache entry produced by encode.
+func (e *resultEncoding) decode(b []byte) (*cachedResult, cacheEntry, error) {
+	entry, err := decodeEntry(b)
+	if err != nil {
+		return nil, entry, err
+	}
+
+	columns, rows, err := e.codec.DecodeRows(entry.payload)
+	if err != nil {
+		return nil, entry, err
+	}
+
+	return &cachedResult{columns: columns, rows: rows}, entry, nil
+}
+
+// fits reports whether a result set of the given encoded size may be cached.
+func (e *resultEncoding) fits(size int) bool {
+	return e.maxSize <= 0 || size <= e.maxSize
+}
+
+// estimateValueSize returns a lower bound of the bytes a value takes once encoded,
+// to stop recording a result set as soon as it is too large to be cached.
+func estimateValueSize(v driver.Value) int {
+	switch t := v.(type) {
+	case []byte:
+		return len(t) + 1
+	case string:
+		return len(t) + 1
+	case time.Time:
+		return 16
+	default:
+		return 1
+	}
+}
+
+// WithRowCodec returns an option that sets how result sets are encoded in the cache,
+// BinaryRowCodec by default. Changing the codec of a running service should come with
+// a new cache namespace version, entries written by the other codec cannot be read.
+func WithRowCodec(codec RowCodec) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.encoding.codec = codec
+	})
+}
+
+// WithCompression returns an option that compresses the cached result sets whose encoded size
+// is at least threshold bytes.
+func WithCompression(compression Compression, threshold int) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.encoding.compression = compression
+		x.encoding.threshold = threshold
+	})
+}
+
+// WithMaxCachedResultSize returns an option that only caches result sets whose encoded size,
+// before compression, is at most n bytes. Larger result sets are read from the database every time.
+func WithMaxCachedResultSize(n int) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.encoding.maxSize = n
+	})
+}
@@ -0,0 +1,104 @@
+package rdbx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbx_QueryContext_encoding(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	query := "SELECT id, bio FROM users"
+	bio := strings.Repeat("loves long walks on the beach. ", 20)
+
+	queryAll := func(t *testing.T, dbx *dbx) (*Rows, [][2]string) {
+		rows, err := dbx.QueryContext(context.Background(), query)
+		assert.NoError(t, err)
+
+		var got [][2]string
+		for rows.Next() {
+			var v [2]string
+			assert.NoError(t, rows.Scan(&v[0], &v[1]))
+			got = append(got, v)
+		}
+
+		assert.NoError(t, rows.Close())
+
+		return rows, got
+	}
+
+	expectQuery := func(n int) {
+		rows := sqlmock.NewRows([]string{"id", "bio"})
+		for i := 0; i < n; i++ {
+			rows.AddRow(i, bio)
+		}
+
+		mock.ExpectQuery(query).WillReturnRows(rows)
+	}
+
+	t.Run("json codec with compression", func(t *testing.T) {
+		c := newMapCache()
+		dbx := NewDbx(db, c, WithRowCodec(JSONRowCodec), WithCompression(GzipCompression, 512))
+
+		expectQuery(3)
+		_, fromDB := queryAll(t, dbx)
+
+		assert.Len(t, c.values, 1)
+		for _, v := range c.values {
+			entry, err := decodeEntry(v)
+			assert.NoError(t, err)
+			assert.Equal(t, GzipCompression, entry.compression)
+			assert.Contains(t, string(entry.payload), `{"string":"loves long walks`)
+			assert.Less(t, len(v), len(entry.payload))
+		}
+
+		rows, fromCache := queryAll(t, dbx)
+		assert.True(t, rows.Cached())
+		assert.Equal(t, fromDB, fromCache)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("small payloads are not compressed", func(t *testing.T) {
+		c := newMapCache()
+		dbx := NewDbx(db, c, WithCompression(SnappyCompression, 1<<20))
+
+		expectQuery(1)
+		queryAll(t, dbx)
+
+		for _, v := range c.values {
+			entry, err := decodeEntry(v)
+			assert.NoError(t, err)
+			assert.Equal(t, NoCompression, entry.compression)
+		}
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("result sets over the maximum size are not cached", func(t *testing.T) {
+		c := newMapCache()
+		dbx := NewDbx(db, c, WithMaxCachedResultSize(4*len(bio)))
+
+		expectQuery(3)
+		queryAll(t, dbx)
+		assert.Len(t, c.values, 1)
+
+		c = newMapCache()
+		dbx.cache = c
+
+		expectQuery(10)
+		rows, got := queryAll(t, dbx)
+		assert.False(t, rows.Cached())
+		assert.Len(t, got, 10)
+		assert.Empty(t, c.values)
+
+		expectQuery(10)
+		rows, _ = queryAll(t, dbx)
+		assert.False(t, rows.Cached())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
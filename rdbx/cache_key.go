@@ -0,0 +1,84 @@
+package rdbx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// defaultCacheNamespace prefixes cache keys when no namespace is configured.
+const defaultCacheNamespace = "rdbx"
+
+// KeyBuilder builds the cache key of a query and its arguments.
+type KeyBuilder interface {
+	Key(query string, args ...interface{}) (string, error)
+	// TagKey returns the key of the list holding the cache keys tagged with the tag.
+	TagKey(tag string) string
+}
+
+// hashKeyBuilder builds cache keys as "<namespace>:<version>:<hash>", where the
+// hash covers the query and its normalized arguments.
+type hashKeyBuilder struct {
+	prefix string
+}
+
+// NewHashKeyBuilder creates a KeyBuilder that hashes the query and its arguments with SHA-256
+// and prefixes the hash with the namespace and version, e.g. "billing:v2:<hash>".
+// An empty namespace defaults to "rdbx", an empty version is left out.
+func NewHashKeyBuilder(namespace, version string) KeyBuilder {
+	if namespace == "" {
+		namespace = defaultCacheNamespace
+	}
+
+	parts := []string{namespace}
+	if version != "" {
+		parts = append(parts, version)
+	}
+
+	return &hashKeyBuilder{prefix: strings.Join(parts, ":") + ":"}
+}
+
+// Key returns the cache key of the query and its arguments.
+// Arguments are normalized the same way database/sql converts them for the driver,
+// so int(1) and int64(1), or a driver.Valuer and its value, produce the same key.
+func (b *hashKeyBuilder) Key(query string, args ...interface{}) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	writeBytes(buf, []byte(query))
+	writeUvarint(buf, uint64(len(args)))
+
+	for i, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			writeBytes(buf, []byte(named.Name))
+			arg = named.Value
+		} else {
+			writeBytes(buf, nil)
+		}
+
+		v, err := normalizeArg(arg)
+		if err != nil {
+			return "", fmt.Errorf("rdbx: cache key argument %d: %w", i, err)
+		}
+
+		if err := encodeValue(buf, v); err != nil {
+			return "", fmt.Errorf("rdbx: cache key argument %d: %w", i, err)
+		}
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	return b.prefix + hex.EncodeToString(sum[:]), nil
+}
+
+// TagKey returns "<namespace>:<version>:tag:<tag>".
+func (b *hashKeyBuilder) TagKey(tag string) string {
+	return b.prefix + "tag:" + tag
+}
+
+// normalizeArg converts a query argument to the driver value database/sql would send.
+func normalizeArg(arg interface{}) (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(arg)
+}
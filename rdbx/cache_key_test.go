@@ -0,0 +1,54 @@
+package rdbx
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_hashKeyBuilder_Key(t *testing.T) {
+	query := "SELECT * FROM users WHERE id = ?"
+
+	key := func(kb KeyBuilder, query string, args ...interface{}) string {
+		k, err := kb.Key(query, args...)
+		assert.NoError(t, err)
+
+		return k
+	}
+
+	kb := NewHashKeyBuilder("", "")
+
+	t.Run("arguments are part of the key", func(t *testing.T) {
+		assert.NotEqual(t, key(kb, query, 1), key(kb, query, 2))
+		assert.NotEqual(t, key(kb, query, "1"), key(kb, query, 1))
+		assert.NotEqual(t, key(kb, query, 1, 2), key(kb, query, 12))
+	})
+
+	t.Run("arguments are normalized", func(t *testing.T) {
+		id := int64(1)
+
+		assert.Equal(t, key(kb, query, 1), key(kb, query, int64(1)))
+		assert.Equal(t, key(kb, query, uint8(1)), key(kb, query, &id))
+		assert.Equal(t, key(kb, query, sql.NullInt64{Int64: 1, Valid: true}), key(kb, query, 1))
+		assert.Equal(t, key(kb, query, sql.NullInt64{}), key(kb, query, nil))
+	})
+
+	t.Run("named arguments", func(t *testing.T) {
+		assert.NotEqual(t, key(kb, query, sql.Named("id", 1)), key(kb, query, sql.Named("uid", 1)))
+		assert.NotEqual(t, key(kb, query, sql.Named("id", 1)), key(kb, query, 1))
+	})
+
+	t.Run("namespace and version", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(key(kb, query), "rdbx:"))
+		assert.True(t, strings.HasPrefix(key(NewHashKeyBuilder("billing", "v2"), query), "billing:v2:"))
+		assert.NotEqual(t, key(NewHashKeyBuilder("billing", "v1"), query), key(NewHashKeyBuilder("billing", "v2"), query))
+	})
+
+	t.Run("unsupported argument", func(t *testing.T) {
+		_, err := kb.Key(query, time.Now, struct{}{})
+		assert.Error(t, err)
+	})
+}
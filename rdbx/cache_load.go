@@ -0,0 +1,349 @@
+package rdbx
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+)
+
+// Markers at the start of the payload of a GetOrLoad entry.
+const (
+	loadNotFound byte = iota
+	loadFound
+)
+
+// Codec encodes the values stored by GetOrLoad.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes values as JSON, it is the default codec of GetOrLoad.
+var JSONCodec Codec = jsonCodec{}
+
+// GobCodec encodes values with encoding/gob.
+var GobCodec Codec = gobCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// loadFlights coalesces concurrent GetOrLoad calls of the same key in this process.
+var loadFlights flightGroup[loadFlightKey]
+
+// loadFlightKey identifies the GetOrLoad calls that share a load: the same key of the same
+// cache, loaded into the same type.
+type loadFlightKey struct {
+	cache Cache
+	typ   reflect.Type
+	key   string
+}
+
+// loadOptions configures GetOrLoad.
+type loadOptions struct {
+	codec       Codec
+	negativeTTL time.Duration
+
+	rsync      *redsync.Redsync
+	lockWait   time.Duration
+	lockExpiry time.Duration
+
+	earlyRefreshBeta float64
+}
+
+// GetOrLoad returns the value cached under the key, or loads it with loader and caches it for ttl.
+// The loader signals a missing value by returning sql.ErrNoRows, possibly wrapped: GetOrLoad
+// then returns sql.ErrNoRows, and caches the absence when WithNegativeTTL is set.
+// Concurrent calls of the same key, cache and type in this process run the loader once; when it
+// panics, the waiting calls fail with an error. Cache errors are logged, the value is then loaded
+// as on a miss.
+func GetOrLoad[T any](
+	ctx context.Context,
+	c Cache,
+	key string,
+	ttl time.Duration,
+	loader func(ctx context.Context) (T, error),
+	options ...LoadOption,
+) (T, error) {
+	o := &loadOptions{
+		codec:      JSONCodec,
+		lockExpiry: defaultRecomputeLockExpiry,
+	}
+
+	for _, opt := range options {
+		opt.Apply(o)
+	}
+
+	var zero T
+
+	stale, fresh, ok := getLoaded[T](ctx, c, key, o)
+	if ok && fresh {
+		return stale.result()
+	}
+
+	flight := loadFlightKey{cache: c, typ: reflect.TypeOf((*T)(nil)).Elem(), key: key}
+
+	// A cache of an uncomparable type cannot key the flights, its calls are not coalesced.
+	if !reflect.TypeOf(c).Comparable() {
+		res, err := load(ctx, c, key, ttl, loader, o, stale, ok)
+		if err != nil {
+			return zero, err
+		}
+
+		return res.result()
+	}
+
+	call, leader := loadFlights.join(flight)
+	if !leader {
+		if ok {
+			return stale.result()
+		}
+
+		val, err := call.wait(ctx)
+		if res, ok := val.(typedLoadResult[T]); ok && err == nil {
+			return res.result()
+		}
+
+		// The leader's context is not the caller's: a leader that was cancelled or timed out
+		// does not fail the callers whose own context is still live, they load the value themselves.
+		if err != nil && !(isContextError(err) && ctx.Err() == nil) {
+			return zero, err
+		}
+	}
+
+	if leader {
+		// As with a returned error, the waiting calls fail when the loader panics.
+		defer func() {
+			if r := recover(); r != nil {
+				loadFlights.finish(flight, call, nil, fmt.Errorf("rdbx: loader panicked: %v", r))
+				panic(r)
+			}
+		}()
+	}
+
+	res, err := load(ctx, c, key, ttl, loader, o, stale, ok)
+	if leader {
+		loadFlights.finish(flight, call, res, err)
+	}
+
+	if err != nil {
+		return zero, err
+	}
+
+	return res.result()
+}
+
+// typedLoadResult is a loaded value, or the absence of one.
+type typedLoadResult[T any] struct {
+	val   T
+	found bool
+}
+
+// result returns the value, or sql.ErrNoRows when it was not found.
+func (r typedLoadResult[T]) result() (T, error) {
+	if !r.found {
+		return r.val, sql.ErrNoRows
+	}
+
+	return r.val, nil
+}
+
+// load runs the loader, under the recompute lock when one is configured, and caches its result.
+func load[T any](
+	ctx context.Context,
+	c Cache,
+	key string,
+	ttl time.Duration,
+	loader func(ctx context.Context) (T, error),
+	o *loadOptions,
+	stale typedLoadResult[T],
+	hasStale bool,
+) (typedLoadResult[T], error) {
+	unlock, locked := lockRecompute(ctx, o.rsync, key, o.lockExpiry)
+	defer unlock()
+
+	if !locked {
+		if hasStale {
+			return stale, nil
+		}
+
+		var res typedLoadResult[T]
+		if waitRecompute(ctx, o.lockWait, func() bool {
+			var ok bool
+			res, _, ok = getLoaded[T](ctx, c, key, o)
+
+			return ok
+		}) {
+			return res, nil
+		}
+	}
+
+	start := time.Now()
+
+	val, err := loader(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return typedLoadResult[T]{}, err
+	}
+
+	res := typedLoadResult[T]{val: val, found: err == nil}
+
+	entryTTL := ttl
+	if !res.found {
+		entryTTL = o.negativeTTL
+	}
+
+	if entryTTL > 0 {
+		setLoaded(ctx, c, key, res, entryTTL, time.Since(start), o)
+	}
+
+	return res, nil
+}
+
+// getLoaded returns the result cached under the key, and whether it is fresh.
+func getLoaded[T any](ctx context.Context, c Cache, key string, o *loadOptions) (typedLoadResult[T], bool, bool) {
+	var res typedLoadResult[T]
+
+	b, err := c.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, ErrCacheMiss) {
+			log.Printf("cache get error: %v", err)
+		}
+
+		return res, false, false
+	}
+
+	entry, err := decodeEntry(b)
+	if err == nil && len(entry.payload) == 0 {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		log.Printf("cache decode error: %v", err)
+		return res, false, false
+	}
+
+	if entry.payload[0] == loadFound {
+		if err := o.codec.Unmarshal(entry.payload[1:], &res.val); err != nil {
+			log.Printf("cache decode error: %v", err)
+			return res, false, false
+		}
+
+		res.found = true
+	}
+
+	return res, !refreshEarly(entry, o.earlyRefreshBeta, time.Now()), true
+}
+
+// setLoaded caches a loaded result. Errors are logged.
+func setLoaded[T any](
+	ctx context.Context,
+	c Cache,
+	key string,
+	res typedLoadResult[T],
+	ttl time.Duration,
+	delta time.Duration,
+	o *loadOptions,
+) {
+	payload := []byte{loadNotFound}
+
+	if res.found {
+		b, err := o.codec.Marshal(res.val)
+		if err != nil {
+			log.Printf("cache encode error: %v", err)
+			return
+		}
+
+		payload = append([]byte{loadFound}, b...)
+	}
+
+	entry := encodeEntry(cacheEntry{
+		payload:   payload,
+		delta:     delta,
+		expiresAt: time.Now().Add(ttl),
+	})
+
+	if err := c.Set(ctx, key, entry, ttl); err != nil {
+		log.Printf("cache set error: %v", err)
+	}
+}
+
+// LoadOption represents an option for GetOrLoad.
+type LoadOption interface {
+	Apply(*loadOptions)
+}
+
+// loadOptionFunc represents a function that applies an option to GetOrLoad.
+type loadOptionFunc func(*loadOptions)
+
+// Apply applies the option to GetOrLoad.
+func (f loadOptionFunc) Apply(o *loadOptions) {
+	f(o)
+}
+
+// WithCodec returns an option that sets how GetOrLoad encodes values, JSONCodec by default.
+func WithCodec(codec Codec) LoadOption {
+	return loadOptionFunc(func(o *loadOptions) {
+		o.codec = codec
+	})
+}
+
+// WithNegativeTTL returns an option that caches a not found result for ttl,
+// so repeated lookups of a missing key do not all reach the loader.
+func WithNegativeTTL(ttl time.Duration) LoadOption {
+	return loadOptionFunc(func(o *loadOptions) {
+		o.negativeTTL = ttl
+	})
+}
+
+// WithLoadLock returns an option that takes a Redis lock before running the loader,
+// so only one instance loads a missed key while the others wait up to wait for its result.
+func WithLoadLock(rsync *redsync.Redsync, wait time.Duration) LoadOption {
+	return loadOptionFunc(func(o *loadOptions) {
+		o.rsync = rsync
+		o.lockWait = wait
+	})
+}
+
+// WithLoadEarlyRefresh returns an option that reloads cached values shortly before they expire,
+// with a probability that grows as the expiry gets closer, like WithEarlyRefresh.
+func WithLoadEarlyRefresh(beta float64) LoadOption {
+	return loadOptionFunc(func(o *loadOptions) {
+		o.earlyRefreshBeta = beta
+	})
+}
+
+// isContextError reports whether the error comes from a cancelled or expired context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
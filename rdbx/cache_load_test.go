@@ -0,0 +1,221 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+
+	type user struct {
+		ID   int64
+		Name string
+	}
+
+	t.Run("loads once and serves from the cache", func(t *testing.T) {
+		for _, codec := range []Codec{JSONCodec, GobCodec} {
+			c := NewMemoryCache()
+
+			var calls int
+			loader := func(ctx context.Context) (user, error) {
+				calls++
+				return user{ID: 1, Name: "alie"}, nil
+			}
+
+			for i := 0; i < 3; i++ {
+				u, err := GetOrLoad(ctx, c, "user:1", time.Minute, loader, WithCodec(codec))
+				assert.NoError(t, err)
+				assert.Equal(t, user{ID: 1, Name: "alie"}, u)
+			}
+
+			assert.Equal(t, 1, calls)
+		}
+	})
+
+	t.Run("negative caching", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		var calls int
+		loader := func(ctx context.Context) (*user, error) {
+			calls++
+			return nil, fmt.Errorf("user 2: %w", sql.ErrNoRows)
+		}
+
+		for i := 0; i < 3; i++ {
+			_, err := GetOrLoad(ctx, c, "user:2", time.Minute, loader, WithNegativeTTL(time.Minute))
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		}
+
+		assert.Equal(t, 1, calls)
+
+		for i := 0; i < 2; i++ {
+			_, err := GetOrLoad(ctx, c, "user:3", time.Minute, loader)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		}
+
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		var calls int
+		loader := func(ctx context.Context) (int, error) {
+			calls++
+			return 0, errors.New("connection refused")
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := GetOrLoad(ctx, c, "count", time.Minute, loader)
+			assert.EqualError(t, err, "connection refused")
+		}
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("concurrent calls run the loader once", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		var calls int32
+		loader := func(ctx context.Context) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(50 * time.Millisecond)
+
+			return "value", nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				v, err := GetOrLoad(ctx, c, "concurrent", time.Minute, loader)
+				assert.NoError(t, err)
+				assert.Equal(t, "value", v)
+			}()
+		}
+
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("a panicking loader fails the waiting calls", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		recovered := make(chan interface{})
+
+		go func() {
+			defer func() {
+				recovered <- recover()
+			}()
+
+			_, _ = GetOrLoad(ctx, c, "panics", time.Minute, func(ctx context.Context) (string, error) {
+				close(started)
+				<-release
+				panic("boom")
+			})
+		}()
+
+		<-started
+
+		done := make(chan error)
+		go func() {
+			_, err := GetOrLoad(ctx, c, "panics", time.Minute, func(ctx context.Context) (string, error) {
+				return "value", nil
+			})
+			done <- err
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+
+		assert.Equal(t, "boom", <-recovered)
+
+		if err := <-done; assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "loader panicked: boom")
+		}
+
+		v, err := GetOrLoad(ctx, c, "panics", time.Minute, func(ctx context.Context) (string, error) {
+			return "value", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "value", v)
+	})
+
+	t.Run("a cancelled leader does not fail the waiting calls", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		leaderCtx, cancel := context.WithCancel(ctx)
+		started := make(chan struct{})
+		leaderErr := make(chan error)
+
+		go func() {
+			_, err := GetOrLoad(leaderCtx, c, "cancelled", time.Minute, func(ctx context.Context) (string, error) {
+				close(started)
+				<-ctx.Done()
+
+				return "", ctx.Err()
+			})
+			leaderErr <- err
+		}()
+
+		<-started
+
+		done := make(chan string)
+		go func() {
+			v, err := GetOrLoad(ctx, c, "cancelled", time.Minute, func(ctx context.Context) (string, error) {
+				return "value", nil
+			})
+			assert.NoError(t, err)
+			done <- v
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+
+		assert.ErrorIs(t, <-leaderErr, context.Canceled)
+		assert.Equal(t, "value", <-done)
+	})
+
+	t.Run("calls of other caches and types do not share a load", func(t *testing.T) {
+		c1, c2 := NewMemoryCache(), NewMemoryCache()
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+
+		go func() {
+			_, _ = GetOrLoad(ctx, c1, "shared", time.Minute, func(ctx context.Context) (string, error) {
+				close(started)
+				<-release
+				return "c1", nil
+			})
+		}()
+
+		<-started
+		defer close(release)
+
+		v, err := GetOrLoad(ctx, c2, "shared", time.Minute, func(ctx context.Context) (string, error) {
+			return "c2", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "c2", v)
+
+		n, err := GetOrLoad(ctx, c1, "shared", time.Minute, func(ctx context.Context) (int, error) {
+			return 1, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+}
@@ -0,0 +1,480 @@
+package rdbx
+
+import (
+	"container/list"
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default bounds of the in-memory cache.
+const (
+	defaultMemoryCacheMaxEntries = 10000
+	defaultMemoryCacheMaxBytes   = 64 << 20
+)
+
+// errWrongType is returned when a value is read as a list, or a list as a value.
+var errWrongType = errors.New("rdbx: operation against a key holding the wrong kind of value")
+
+// memoryCache is an in-process Cache bounded by number of entries and bytes.
+// When a bound is exceeded the least recently used entries are evicted.
+// Tag sets are kept apart from the entries and are never evicted, so an entry can always be
+// invalidated; they only hold the keys of stored entries, and are not counted in the bounds.
+type memoryCache struct {
+	mu sync.Mutex
+
+	maxEntries int
+	maxBytes   int64
+
+	size  int64
+	ll    *list.List               // Most recently used entries at the front.
+	items map[string]*list.Element // Elements hold *memoryEntry.
+
+	tags    map[string]*memoryTag
+	tagged  map[string][]string // Tag keys of each tagged key.
+	tagSize int64
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	now func() time.Time
+}
+
+// memoryEntry is a value or a list stored in the memory cache.
+type memoryEntry struct {
+	key       string
+	value     []byte
+	list      []string
+	isList    bool
+	expiresAt time.Time // Zero when the entry does not expire.
+}
+
+// memoryTag is a tag set of the memory cache.
+type memoryTag struct {
+	keys      map[string]struct{}
+	expiresAt time.Time // Zero when the set does not expire.
+}
+
+// size returns the number of bytes accounted for the entry.
+func (e *memoryEntry) size() int64 {
+	n := len(e.key) + len(e.value)
+	for _, v := range e.list {
+		n += len(v)
+	}
+
+	return int64(n)
+}
+
+// NewMemoryCache creates an in-process Cache, by default bounded to 10000 entries and 64 MiB.
+// It needs no external service, which suits tests and small services.
+func NewMemoryCache(options ...MemoryCacheOption) *memoryCache {
+	c := &memoryCache{
+		maxEntries: defaultMemoryCacheMaxEntries,
+		maxBytes:   defaultMemoryCacheMaxBytes,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+		tags:       map[string]*memoryTag{},
+		tagged:     map[string][]string{},
+		now:        time.Now,
+	}
+
+	for _, o := range options {
+		o.Apply(c)
+	}
+
+	return c
+}
+
+// Set stores the value under the key, a ttl of zero or less means the key does not expire.
+// Values that are larger than the byte bound on their own are not stored.
+func (c *memoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	b, err := cacheValueBytes(value)
+	if err != nil {
+		return err
+	}
+
+	e := &memoryEntry{key: key, value: b}
+	if ttl > 0 {
+		e.expiresAt = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.put(e)
+
+	return nil
+}
+
+// Get returns the value stored under the key, or ErrCacheMiss.
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.get(key)
+	if !ok {
+		c.misses++
+		return nil, ErrCacheMiss
+	}
+
+	if e.isList {
+		return nil, errWrongType
+	}
+
+	c.hits++
+
+	return append([]byte(nil), e.value...), nil
+}
+
+// Append prepends the value to the list stored under the key and returns the length of the list.
+func (c *memoryCache) Append(ctx context.Context, key string, value interface{}) (int64, error) {
+	b, err := cacheValueBytes(value)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.get(key)
+	if !ok {
+		c.put(&memoryEntry{key: key, list: []string{string(b)}, isList: true})
+		return 1, nil
+	}
+
+	if !e.isList {
+		return 0, errWrongType
+	}
+
+	// Lists are kept oldest first, so appending is cheap, and reversed by GetList.
+	e.list = append(e.list, string(b))
+	c.size += int64(len(b))
+	c.evict()
+
+	return int64(len(e.list)), nil
+}
+
+// GetList returns the list stored under the key, or an empty list when there is none.
+func (c *memoryCache) GetList(ctx context.Context, key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.get(key)
+	if !ok {
+		return []string{}, nil
+	}
+
+	if !e.isList {
+		return nil, errWrongType
+	}
+
+	list := make([]string, len(e.list))
+	for i, v := range e.list {
+		list[len(list)-1-i] = v
+	}
+
+	return list, nil
+}
+
+// Tag adds the key to the tag set. The expiry of the set is extended to ttl when it is shorter.
+func (c *memoryCache) Tag(ctx context.Context, tagKey, key string, ttl time.Duration) error {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.tag(tagKey)
+	if !ok {
+		t = &memoryTag{keys: map[string]struct{}{}, expiresAt: expiresAt}
+		c.tags[tagKey] = t
+		c.tagSize += int64(len(tagKey))
+	} else if !t.expiresAt.IsZero() && (expiresAt.IsZero() || expiresAt.After(t.expiresAt)) {
+		t.expiresAt = expiresAt
+	}
+
+	if _, ok := t.keys[key]; !ok {
+		t.keys[key] = struct{}{}
+		c.tagged[key] = append(c.tagged[key], tagKey)
+		c.tagSize += int64(len(key))
+	}
+
+	return nil
+}
+
+// Delete removes the keys, missing keys are ignored.
+func (c *memoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.drop(el)
+		}
+	}
+
+	return nil
+}
+
+// DeleteByTag removes the keys of the tag set, and the tag set itself.
+func (c *memoryCache) DeleteByTag(ctx context.Context, tagKey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.tag(tagKey)
+	if !ok {
+		return nil
+	}
+
+	c.deleteTag(tagKey)
+
+	for key := range t.keys {
+		if el, ok := c.items[key]; ok {
+			c.drop(el)
+		}
+	}
+
+	return nil
+}
+
+// DeleteByPrefix removes every key starting with the prefix.
+func (c *memoryCache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.drop(el)
+		}
+	}
+
+	return nil
+}
+
+// TTL returns how long the key lives before it expires, zero when it does not expire.
+func (c *memoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.get(key)
+	if !ok {
+		return 0, ErrCacheMiss
+	}
+
+	if e.expiresAt.IsZero() {
+		return 0, nil
+	}
+
+	return e.expiresAt.Sub(c.now()), nil
+}
+
+// Stats returns the hits and misses of Get, the entries evicted to stay within the bounds,
+// and the size of the keys, values and tag sets.
+func (c *memoryCache) Stats(ctx context.Context) (CacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Bytes:     c.size + c.tagSize,
+	}, nil
+}
+
+// get returns the entry of the key and marks it as recently used. Expired entries are removed.
+func (c *memoryCache) get(key string) (*memoryEntry, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*memoryEntry)
+	if !e.expiresAt.IsZero() && !c.now().Before(e.expiresAt) {
+		c.drop(el)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+
+	return e, true
+}
+
+// put stores the entry, replacing the entry of the same key, and evicts entries
+// until the cache is within its bounds again.
+func (c *memoryCache) put(e *memoryEntry) {
+	if el, ok := c.items[e.key]; ok {
+		c.remove(el)
+	}
+
+	if c.maxBytes > 0 && e.size() > c.maxBytes {
+		c.untag(e.key)
+		return
+	}
+
+	c.items[e.key] = c.ll.PushFront(e)
+	c.size += e.size()
+
+	c.evict()
+}
+
+// evict evicts the least recently used entries until the cache is within its bounds.
+func (c *memoryCache) evict() {
+	for c.overflows() {
+		c.drop(c.ll.Back())
+		c.evictions++
+	}
+}
+
+// overflows reports whether the cache exceeds one of its bounds.
+func (c *memoryCache) overflows() bool {
+	return c.maxEntries > 0 && c.ll.Len() > c.maxEntries ||
+		c.maxBytes > 0 && c.size > c.maxBytes
+}
+
+// remove removes the element from the cache, its key staying in its tag sets for the entry
+// that replaces it.
+func (c *memoryCache) remove(el *list.Element) {
+	e := el.Value.(*memoryEntry)
+
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	c.size -= e.size()
+}
+
+// drop removes the element from the cache and its key from its tag sets.
+func (c *memoryCache) drop(el *list.Element) {
+	c.remove(el)
+	c.untag(el.Value.(*memoryEntry).key)
+}
+
+// tag returns the tag set of the tag key. An expired set is removed.
+func (c *memoryCache) tag(tagKey string) (*memoryTag, bool) {
+	t, ok := c.tags[tagKey]
+	if !ok {
+		return nil, false
+	}
+
+	if !t.expiresAt.IsZero() && !c.now().Before(t.expiresAt) {
+		c.deleteTag(tagKey)
+		return nil, false
+	}
+
+	return t, true
+}
+
+// deleteTag removes the tag set of the tag key.
+func (c *memoryCache) deleteTag(tagKey string) {
+	t := c.tags[tagKey]
+
+	delete(c.tags, tagKey)
+	c.tagSize -= int64(len(tagKey))
+
+	for key := range t.keys {
+		c.tagSize -= int64(len(key))
+
+		var tagKeys []string
+		for _, k := range c.tagged[key] {
+			if k != tagKey {
+				tagKeys = append(tagKeys, k)
+			}
+		}
+
+		if len(tagKeys) == 0 {
+			delete(c.tagged, key)
+		} else {
+			c.tagged[key] = tagKeys
+		}
+	}
+}
+
+// untag removes the key from its tag sets, and the sets left empty.
+func (c *memoryCache) untag(key string) {
+	tagKeys := c.tagged[key]
+	delete(c.tagged, key)
+
+	for _, tagKey := range tagKeys {
+		t, ok := c.tags[tagKey]
+		if !ok {
+			continue
+		}
+
+		if _, ok := t.keys[key]; ok {
+			delete(t.keys, key)
+			c.tagSize -= int64(len(key))
+		}
+
+		if len(t.keys) == 0 {
+			c.deleteTag(tagKey)
+		}
+	}
+}
+
+// cacheValueBytes converts a value to bytes the same way the Redis client writes arguments.
+func cacheValueBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case string:
+		return []byte(v), nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+
+		return []byte("0"), nil
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("rdbx: can't marshal %T (implement encoding.BinaryMarshaler)", value)
+	}
+}
+
+// MemoryCacheOption represents an option for the in-memory cache.
+type MemoryCacheOption interface {
+	Apply(*memoryCache)
+}
+
+// memoryCacheOptionFunc represents a function that applies an option to the in-memory cache.
+type memoryCacheOptionFunc func(*memoryCache)
+
+// Apply applies the option to the in-memory cache.
+func (f memoryCacheOptionFunc) Apply(c *memoryCache) {
+	f(c)
+}
+
+// WithMaxEntries returns an option that bounds the number of entries of the in-memory cache,
+// zero or less means no bound.
+func WithMaxEntries(n int) MemoryCacheOption {
+	return memoryCacheOptionFunc(func(c *memoryCache) {
+		c.maxEntries = n
+	})
+}
+
+// WithMaxBytes returns an option that bounds the total size of the keys and values of the
+// in-memory cache, zero or less means no bound.
+func WithMaxBytes(n int64) MemoryCacheOption {
+	return memoryCacheOptionFunc(func(c *memoryCache) {
+		c.maxBytes = n
+	})
+}
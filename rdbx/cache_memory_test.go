@@ -0,0 +1,321 @@
+package rdbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_memoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("set, get and delete", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		_, err := c.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		assert.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+		assert.NoError(t, c.Set(ctx, "b", "2", 0))
+		assert.NoError(t, c.Set(ctx, "c", 3, 0))
+
+		for k, want := range map[string]string{"a": "1", "b": "2", "c": "3"} {
+			got, err := c.Get(ctx, k)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(want), got)
+		}
+
+		assert.NoError(t, c.Delete(ctx, "a", "b", "missing"))
+
+		_, err = c.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		assert.Error(t, c.Set(ctx, "d", struct{}{}, 0))
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		now := time.Now()
+
+		c := NewMemoryCache()
+		c.now = func() time.Time { return now }
+
+		assert.NoError(t, c.Set(ctx, "a", "1", time.Minute))
+		assert.NoError(t, c.Set(ctx, "b", "2", 0))
+
+		now = now.Add(time.Minute)
+
+		_, err := c.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		_, err = c.Get(ctx, "b")
+		assert.NoError(t, err)
+	})
+
+	t.Run("evicts least recently used entries", func(t *testing.T) {
+		c := NewMemoryCache(WithMaxEntries(2))
+
+		assert.NoError(t, c.Set(ctx, "a", "1", 0))
+		assert.NoError(t, c.Set(ctx, "b", "2", 0))
+
+		_, err := c.Get(ctx, "a")
+		assert.NoError(t, err)
+
+		assert.NoError(t, c.Set(ctx, "c", "3", 0))
+
+		_, err = c.Get(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		_, err = c.Get(ctx, "a")
+		assert.NoError(t, err)
+	})
+
+	t.Run("bounded by bytes", func(t *testing.T) {
+		c := NewMemoryCache(WithMaxBytes(9))
+
+		assert.NoError(t, c.Set(ctx, "a", "1234", 0))
+		assert.NoError(t, c.Set(ctx, "b", "1234", 0))
+
+		_, err := c.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		assert.NoError(t, c.Set(ctx, "c", "12345678910", 0))
+
+		_, err = c.Get(ctx, "c")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		_, err = c.Get(ctx, "b")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), c.size)
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		list, err := c.GetList(ctx, "l")
+		assert.NoError(t, err)
+		assert.Empty(t, list)
+
+		n, err := c.Append(ctx, "l", "a")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		n, err = c.Append(ctx, "l", "b")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		list, err = c.GetList(ctx, "l")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b", "a"}, list)
+
+		_, err = c.Get(ctx, "l")
+		assert.ErrorIs(t, err, errWrongType)
+	})
+
+	t.Run("delete by tag and prefix", func(t *testing.T) {
+		c := NewMemoryCache()
+
+		for _, k := range []string{"rdbx:a", "rdbx:b", "other:c"} {
+			assert.NoError(t, c.Set(ctx, k, "1", 0))
+		}
+
+		assert.NoError(t, c.Tag(ctx, "rdbx:tag:users", "rdbx:a", 0))
+		assert.NoError(t, c.Tag(ctx, "rdbx:tag:users", "rdbx:a", 0))
+
+		assert.NoError(t, c.DeleteByTag(ctx, "rdbx:tag:users"))
+
+		_, err := c.Get(ctx, "rdbx:a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.Empty(t, c.tags)
+		assert.Empty(t, c.tagged)
+
+		assert.NoError(t, c.DeleteByPrefix(ctx, "rdbx:"))
+
+		_, err = c.Get(ctx, "rdbx:b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		_, err = c.Get(ctx, "other:c")
+		assert.NoError(t, err)
+	})
+
+	t.Run("tag sets are not evicted", func(t *testing.T) {
+		c := NewMemoryCache(WithMaxEntries(2))
+
+		assert.NoError(t, c.Tag(ctx, "rdbx:tag:users", "rdbx:a", 0))
+		assert.NoError(t, c.Set(ctx, "rdbx:a", "1", 0))
+
+		for _, k := range []string{"rdbx:b", "rdbx:c"} {
+			assert.NoError(t, c.Tag(ctx, "rdbx:tag:orders", k, 0))
+			assert.NoError(t, c.Set(ctx, k, "1", 0))
+		}
+
+		// rdbx:a was evicted and left its tag set, which went with it.
+		assert.NotContains(t, c.tags, "rdbx:tag:users")
+		assert.Len(t, c.tags["rdbx:tag:orders"].keys, 2)
+
+		_, err := c.Get(ctx, "rdbx:b")
+		assert.NoError(t, err)
+
+		assert.NoError(t, c.DeleteByTag(ctx, "rdbx:tag:orders"))
+
+		_, err = c.Get(ctx, "rdbx:b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.Zero(t, c.tagSize)
+	})
+
+	t.Run("tag sets outlive their keys", func(t *testing.T) {
+		now := time.Now()
+
+		c := NewMemoryCache()
+		c.now = func() time.Time { return now }
+
+		assert.NoError(t, c.Tag(ctx, "rdbx:tag:users", "rdbx:a", time.Minute))
+		assert.NoError(t, c.Tag(ctx, "rdbx:tag:users", "rdbx:b", time.Second))
+		assert.NoError(t, c.Set(ctx, "rdbx:a", "1", time.Minute))
+
+		now = now.Add(30 * time.Second)
+
+		assert.NoError(t, c.DeleteByTag(ctx, "rdbx:tag:users"))
+
+		_, err := c.Get(ctx, "rdbx:a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("ttl inspection", func(t *testing.T) {
+		now := time.Now()
+
+		c := NewMemoryCache()
+		c.now = func() time.Time { return now }
+
+		assert.NoError(t, c.Set(ctx, "a", "1", time.Minute))
+		assert.NoError(t, c.Set(ctx, "b", "2", 0))
+
+		now = now.Add(10 * time.Second)
+
+		ttl, err := c.TTL(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 50*time.Second, ttl)
+
+		ttl, err = c.TTL(ctx, "b")
+		assert.NoError(t, err)
+		assert.Zero(t, ttl)
+
+		_, err = c.TTL(ctx, "missing")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		c := NewMemoryCache(WithMaxEntries(1))
+
+		assert.NoError(t, c.Set(ctx, "a", "1", 0))
+		assert.NoError(t, c.Set(ctx, "b", "2", 0))
+
+		_, err := c.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		_, err = c.Get(ctx, "b")
+		assert.NoError(t, err)
+
+		stats, err := c.Stats(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Evictions: 1, Bytes: 2}, stats)
+	})
+
+	t.Run("with dbx", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		type model struct {
+			ID   int64  `column:"id"`
+			Name string `column:"name"`
+		}
+
+		dbx := NewDbx(db, NewMemoryCache())
+
+		mock.ExpectQuery("SELECT id, name FROM users WHERE id = ?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alie"))
+
+		for i := 0; i < 2; i++ {
+			var m model
+			assert.NoError(t, dbx.Queryx(ctx, "SELECT * FROM users WHERE id = ?", &m, 1))
+			assert.Equal(t, model{ID: 1, Name: "alie"}, m)
+		}
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("with dbx, rows read before a write are not cached", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		dbx := NewDbx(db, NewMemoryCache())
+		query := "SELECT name FROM users WHERE id = ?"
+
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("old"))
+
+		rows, err := dbx.QueryContext(ctx, query, 1)
+		assert.NoError(t, err)
+		for rows.Next() {
+		}
+
+		mock.ExpectExec("UPDATE users SET name = ? WHERE id = ?").
+			WithArgs("new", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		_, err = dbx.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", "new", 1)
+		assert.NoError(t, err)
+
+		assert.NoError(t, rows.Close())
+
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("new"))
+
+		var name string
+		assert.NoError(t, dbx.Queryx(ctx, query, &name, 1))
+		assert.Equal(t, "new", name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("with dbx, writes invalidate a full cache", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		dbx := NewDbx(db, NewMemoryCache(WithMaxEntries(4)))
+
+		queryName := func() string {
+			var name string
+			assert.NoError(t, dbx.Queryx(ctx, "SELECT name FROM users WHERE id = ?", &name, 1))
+			return name
+		}
+
+		mock.ExpectQuery("SELECT name FROM users WHERE id = ?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("old"))
+		assert.Equal(t, "old", queryName())
+
+		for _, table := range []string{"orders", "items", "carts"} {
+			query := "SELECT id FROM " + table
+			mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+			rows, err := dbx.QueryContext(ctx, query)
+			assert.NoError(t, err)
+			for rows.Next() {
+			}
+			assert.NoError(t, rows.Close())
+		}
+
+		mock.ExpectExec("UPDATE users SET name = ? WHERE id = ?").
+			WithArgs("new", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		_, err = dbx.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", "new", 1)
+		assert.NoError(t, err)
+
+		mock.ExpectQuery("SELECT name FROM users WHERE id = ?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("new"))
+		assert.Equal(t, "new", queryName())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -1,22 +1,117 @@
 package rdbx
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// redisScanCount is the number of keys asked for by each SCAN of a prefix invalidation.
+const redisScanCount = 1000
+
+// tagScript adds ARGV[1] to the tag set KEYS[1], and extends the expiry of the set to ARGV[2]
+// milliseconds, or removes it for zero. The expiry is never shortened, so the set outlives every
+// key it holds.
+var tagScript = redis.NewScript(`
+local pttl = redis.call('PTTL', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl <= 0 then
+	redis.call('PERSIST', KEYS[1])
+elseif pttl == -2 or pttl >= 0 and pttl < ttl then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// popTagScript deletes the tag set KEYS[1] and returns the keys it held.
+var popTagScript = redis.NewScript(`
+local keys = redis.call('SMEMBERS', KEYS[1])
+redis.call('DEL', KEYS[1])
+return keys
+`)
+
+// cache is a Cache backed by Redis.
 type cache struct {
 	redis redis.UniversalClient
+
+	hits   uint64
+	misses uint64
 }
 
-func (c *cache) Set(ctx context.Context, key string, value interface{}) error {
-	return c.redis.Set(ctx, key, value, 1800*time.Second).Err()
+// NewRedisCache creates a Cache that stores query results in Redis.
+func NewRedisCache(client redis.UniversalClient) *cache {
+	return &cache{
+		redis: client,
+	}
+}
+
+func (c *cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.redis.Set(ctx, key, value, ttl).Err()
 }
 
 func (c *cache) Get(ctx context.Context, key string) ([]byte, error) {
-	return c.redis.Get(ctx, key).Bytes()
+	b, err := c.redis.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, ErrCacheMiss
+	}
+
+	if err == nil {
+		atomic.AddUint64(&c.hits, 1)
+	}
+
+	return b, err
+}
+
+// getWithTTL returns the value of the key and how long it lives, zero when it does not expire,
+// in a single round trip. The ttl is negative when the key expired right after it was read.
+func (c *cache) getWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	var (
+		get  *redis.StringCmd
+		pttl *redis.DurationCmd
+	)
+
+	// The error of the pipeline is the error of its first failed command, read below.
+	_, _ = c.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+
+		return nil
+	})
+
+	b, err := get.Bytes()
+	if errors.Is(err, redis.Nil) {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, 0, ErrCacheMiss
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+
+	ttl, err := pttl.Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// PTTL replies -2 when the key does not exist, and -1 when it has no expiry.
+	switch ttl {
+	case -2:
+		return b, -1, nil
+	case -1:
+		return b, 0, nil
+	}
+
+	return b, ttl, nil
 }
 
 func (c *cache) Append(ctx context.Context, key string, value interface{}) (int64, error) {
@@ -26,3 +121,165 @@ func (c *cache) Append(ctx context.Context, key string, value interface{}) (int6
 func (c *cache) GetList(ctx context.Context, key string) ([]string, error) {
 	return c.redis.LRange(ctx, key, 0, -1).Result() // -1 means all
 }
+
+// Tag adds the key to the tag set, a Redis set whose expiry is extended to ttl when it is shorter.
+func (c *cache) Tag(ctx context.Context, tagKey, key string, ttl time.Duration) error {
+	return tagScript.Run(ctx, c.redis, []string{tagKey}, key, ttl.Milliseconds()).Err()
+}
+
+// Delete removes the keys with one DEL each in a pipeline, so they may belong to different
+// cluster slots.
+func (c *cache) Delete(ctx context.Context, keys ...string) error {
+	return deleteKeys(ctx, c.redis, keys)
+}
+
+// DeleteByTag removes the tag set and the keys it held. The set is read and removed at once,
+// so a key tagged meanwhile is either removed or kept in a new set for the next invalidation.
+func (c *cache) DeleteByTag(ctx context.Context, tagKey string) error {
+	keys, err := c.popTag(ctx, tagKey)
+	if err != nil {
+		return err
+	}
+
+	return c.Delete(ctx, keys...)
+}
+
+// popTag removes the tag set and returns the keys it held.
+func (c *cache) popTag(ctx context.Context, tagKey string) ([]string, error) {
+	return popTagScript.Run(ctx, c.redis, []string{tagKey}).StringSlice()
+}
+
+// DeleteByPrefix removes the keys matching the prefix with SCAN, so Redis is never blocked
+// by a KEYS command. On a Redis Cluster every master is scanned.
+func (c *cache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	match := escapeRedisPattern(prefix) + "*"
+
+	if cluster, ok := c.redis.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scanDelete(ctx, node, match)
+		})
+	}
+
+	return scanDelete(ctx, c.redis, match)
+}
+
+func (c *cache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.redis.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL replies -2 when the key does not exist, and -1 when it has no expiry.
+	switch ttl {
+	case -2:
+		return 0, ErrCacheMiss
+	case -1:
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Stats returns the hits and misses of this client, and the evictions and memory
+// reported by the Redis server, which cover every client of the server.
+func (c *cache) Stats(ctx context.Context) (CacheStats, error) {
+	stats := CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+
+	info, err := c.redis.Info(ctx).Result()
+	if err != nil {
+		return stats, err
+	}
+
+	fields := parseRedisInfo(info)
+
+	if v, ok := fields["evicted_keys"]; ok {
+		if stats.Evictions, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return stats, err
+		}
+	}
+
+	if v, ok := fields["used_memory"]; ok {
+		if stats.Bytes, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+// scanDelete deletes the keys of a single Redis node matching the pattern, a batch at a time.
+func scanDelete(ctx context.Context, client redis.Cmdable, match string) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := client.Scan(ctx, cursor, match, redisScanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		if err := deleteKeys(ctx, client, keys); err != nil {
+			return err
+		}
+
+		if next == 0 {
+			return nil
+		}
+
+		cursor = next
+	}
+}
+
+// deleteKeys deletes the keys with one command each in a pipeline, as a multi-key DEL fails
+// with CROSSSLOT when the keys belong to different cluster slots.
+func deleteKeys(ctx context.Context, client redis.Cmdable, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pipe := client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// escapeRedisPattern escapes the glob characters of a SCAN pattern.
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// parseRedisInfo parses the "field:value" lines of an INFO reply.
+func parseRedisInfo(info string) map[string]string {
+	fields := map[string]string{}
+
+	sc := bufio.NewScanner(strings.NewReader(info))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[k] = v
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,102 @@
+package rdbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cache(t *testing.T) {
+	ctx := context.Background()
+
+	redisClient, rMock := redismock.NewClientMock()
+
+	newCache := func() *cache {
+		rMock.ClearExpect()
+		return NewRedisCache(redisClient)
+	}
+
+	t.Run("delete by tag", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectEvalSha(popTagScript.Hash(), []string{"rdbx:tag:users"}).SetVal([]interface{}{"rdbx:a", "rdbx:b"})
+		rMock.ExpectDel("rdbx:a").SetVal(1)
+		rMock.ExpectDel("rdbx:b").SetVal(1)
+
+		assert.NoError(t, c.DeleteByTag(ctx, "rdbx:tag:users"))
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("tag adds the key to the tag set", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectEvalSha(tagScript.Hash(), []string{"rdbx:tag:users"}, "rdbx:a", int64(90000)).SetVal(int64(1))
+
+		assert.NoError(t, c.Tag(ctx, "rdbx:tag:users", "rdbx:a", 90*time.Second))
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("delete sends one command per key", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectDel("a").SetVal(1)
+		rMock.ExpectDel("b").SetVal(0)
+
+		assert.NoError(t, c.Delete(ctx, "a", "b"))
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("delete by prefix scans the keys", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectScan(0, `rdbx:v\[1\]:*`, redisScanCount).SetVal([]string{"rdbx:v[1]:a"}, 7)
+		rMock.ExpectDel("rdbx:v[1]:a").SetVal(1)
+		rMock.ExpectScan(7, `rdbx:v\[1\]:*`, redisScanCount).SetVal([]string{}, 0)
+
+		assert.NoError(t, c.DeleteByPrefix(ctx, "rdbx:v[1]:"))
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectPTTL("a").SetVal(1500 * time.Millisecond)
+		rMock.ExpectPTTL("b").SetVal(-1)
+		rMock.ExpectPTTL("c").SetVal(-2)
+
+		ttl, err := c.TTL(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 1500*time.Millisecond, ttl)
+
+		ttl, err = c.TTL(ctx, "b")
+		assert.NoError(t, err)
+		assert.Zero(t, ttl)
+
+		_, err = c.TTL(ctx, "c")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectGet("a").SetVal("1")
+		rMock.ExpectGet("b").SetErr(redis.Nil)
+		rMock.ExpectInfo().SetVal("# Memory\r\nused_memory:1024\r\n\r\n# Stats\r\nevicted_keys:3\r\n")
+
+		_, err := c.Get(ctx, "a")
+		assert.NoError(t, err)
+
+		_, err = c.Get(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		stats, err := c.Stats(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, CacheStats{Hits: 1, Misses: 1, Evictions: 3, Bytes: 1024}, stats)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,320 @@
+package rdbx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+)
+
+// Defaults of the recompute lock.
+const (
+	defaultRecomputeLockExpiry = 30 * time.Second
+	recomputeLockPollInterval  = 50 * time.Millisecond
+)
+
+// flightGroup coalesces concurrent loads of the same key in this process:
+// the first caller loads the value, the others wait for its result.
+// The zero value is ready to use.
+type flightGroup[K comparable] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall
+}
+
+// flightCall is a load in progress.
+type flightCall struct {
+	done     chan struct{}
+	released chan struct{} // Closed once later callers should not wait for the result.
+	val      interface{}
+	err      error
+}
+
+// join returns the load in progress for the key, and whether the caller is the leader
+// that must load the value and finish the call.
+func (g *flightGroup[K]) join(key K) (*flightCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.calls == nil {
+		g.calls = map[K]*flightCall{}
+	}
+
+	if c, ok := g.calls[key]; ok {
+		return c, false
+	}
+
+	c := &flightCall{done: make(chan struct{}), released: make(chan struct{})}
+	g.calls[key] = c
+
+	return c, true
+}
+
+// finish publishes the result of the leader to the waiting callers.
+func (g *flightGroup[K]) finish(key K, c *flightCall, val interface{}, err error) {
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+
+	c.val, c.err = val, err
+	close(c.done)
+}
+
+// release tells the callers that join the call from now on not to wait for its result.
+// The callers already waiting still get it.
+func (c *flightCall) release() {
+	close(c.released)
+}
+
+// isReleased reports whether release was called.
+func (c *flightCall) isReleased() bool {
+	select {
+	case <-c.released:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait waits for the leader's result, or for the context to be done.
+func (c *flightCall) wait(ctx context.Context) (interface{}, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// wait waits for the leader's result for at most timeout, or for the context to be done.
+// A leader that takes longer, e.g. because its Rows are never closed, is abandoned: the call
+// leaves the group so the next caller of the key leads a new one, and errFlightAbandoned is returned.
+func (g *flightGroup[K]) wait(ctx context.Context, key K, c *flightCall, timeout time.Duration) (interface{}, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		return nil, errFlightAbandoned
+	}
+}
+
+// errFlightAbandoned is the result of a load whose leader could not produce a cacheable result.
+var errFlightAbandoned = errors.New("rdbx: load abandoned")
+
+// cachedQuery runs a cacheable query. Concurrent misses of the same key in this process are
+// coalesced, and with WithRecomputeLock only one instance recomputes a key at a time while
+// the others wait for its result. Followers wait for the leader as long as a recompute lock
+// lasts, then run the query themselves. Once the leader has returned its Rows, which may be
+// held open while the same goroutine runs the query again, new followers do not wait at all.
+func (x *dbx) cachedQuery(
+	ctx context.Context,
+	target *cacheTarget,
+	mode cacheMode,
+	query string,
+	args ...interface{},
+) (*Rows, error) {
+	var stale *cachedResult
+
+	if mode != cacheModeRefresh {
+		res, fresh, ok := x.cachedResult(ctx, target)
+		if ok && fresh {
+			return newCachedRows(ctx, res)
+		}
+
+		if ok {
+			stale = res
+		}
+	}
+
+	call, leader := x.flights.join(target.key)
+	if !leader {
+		if stale != nil {
+			return newCachedRows(ctx, stale)
+		}
+
+		if !call.isReleased() {
+			val, err := x.flights.wait(ctx, target.key, call, x.recomputeLockExpiry)
+			if res, ok := val.(*cachedResult); ok && err == nil {
+				return newCachedRows(ctx, res)
+			}
+
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+		}
+
+		rows, err := x.query(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		return newRows(ctx, rows, target), nil
+	}
+
+	unlock, locked := x.lockRecompute(ctx, target)
+	if !locked {
+		res := stale
+		if res == nil && mode != cacheModeRefresh {
+			res = x.waitRecompute(ctx, target)
+		}
+
+		if res != nil {
+			x.flights.finish(target.key, call, res, nil)
+			return newCachedRows(ctx, res)
+		}
+	}
+
+	rows, err := x.query(ctx, query, args...)
+	if err != nil {
+		unlock()
+		x.flights.finish(target.key, call, nil, err)
+
+		return nil, err
+	}
+
+	r := newRows(ctx, rows, target)
+	r.onClose = func(res *cachedResult) {
+		unlock()
+
+		if res == nil {
+			x.flights.finish(target.key, call, nil, errFlightAbandoned)
+			return
+		}
+
+		x.flights.finish(target.key, call, res, nil)
+	}
+
+	call.release()
+
+	return r, nil
+}
+
+// lockRecompute takes the Redis lock that lets a single instance recompute the key.
+// It reports whether the caller may recompute, which is always the case without WithRecomputeLock.
+// The returned function releases the lock.
+func (x *dbx) lockRecompute(ctx context.Context, target *cacheTarget) (func(), bool) {
+	return lockRecompute(ctx, x.rsync, target.key, x.recomputeLockExpiry)
+}
+
+// waitRecompute waits for another instance to store the result of the key,
+// for at most the recompute wait. It returns nil when no result was stored in time.
+func (x *dbx) waitRecompute(ctx context.Context, target *cacheTarget) *cachedResult {
+	var res *cachedResult
+
+	waitRecompute(ctx, x.recomputeWait, func() bool {
+		var ok bool
+		res, _, ok = x.cachedResult(ctx, target)
+
+		return ok
+	})
+
+	return res
+}
+
+// refreshEarly reports whether a cached entry should be recomputed before it expires.
+func (x *dbx) refreshEarly(e cacheEntry) bool {
+	return refreshEarly(e, x.earlyRefreshBeta, x.now())
+}
+
+// lockRecompute takes a Redis lock named after the key with a single try. It reports whether
+// the caller may recompute the key, which is the case when it holds the lock, when rsync is nil,
+// or when Redis cannot be reached. The returned function releases the lock.
+func lockRecompute(ctx context.Context, rsync *redsync.Redsync, key string, expiry time.Duration) (func(), bool) {
+	if rsync == nil {
+		return func() {}, true
+	}
+
+	m := rsync.NewMutex(
+		key+":lock",
+		redsync.WithTries(1),
+		redsync.WithExpiry(expiry),
+	)
+
+	if err := m.LockContext(ctx); err != nil {
+		if !errors.Is(err, redsync.ErrFailed) {
+			log.Printf("cache recompute lock error: %v", err)
+			return func() {}, true
+		}
+
+		return func() {}, false
+	}
+
+	return func() {
+		if ok, err := m.UnlockContext(context.Background()); err != nil || !ok {
+			log.Printf("cache recompute unlock error: %v:%v", err, ok)
+		}
+	}, true
+}
+
+// waitRecompute polls until found reports that another instance stored the result,
+// for at most wait. It reports whether the result was found.
+func waitRecompute(ctx context.Context, wait time.Duration, found func() bool) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(recomputeLockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return false
+		case <-ticker.C:
+			if found() {
+				return true
+			}
+		}
+	}
+}
+
+// refreshEarly implements probabilistic early expiration (XFetch): the closer the entry is to
+// its expiry, and the longer it took to compute, the more likely a caller recomputes it.
+func refreshEarly(e cacheEntry, beta float64, now time.Time) bool {
+	if beta <= 0 || e.delta <= 0 {
+		return false
+	}
+
+	gap := float64(e.delta) * beta * -math.Log(1-rand.Float64()) //nolint:gosec
+	if gap > float64(math.MaxInt64) {
+		return true
+	}
+
+	return !now.Add(time.Duration(gap)).Before(e.expiresAt)
+}
+
+// WithRecomputeLock returns an option that takes a Redis lock before recomputing a missed key,
+// so only one instance runs the query while the others wait up to wait for its result.
+func WithRecomputeLock(rsync *redsync.Redsync, wait time.Duration) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.rsync = rsync
+		x.recomputeWait = wait
+	})
+}
+
+// WithEarlyRefresh returns an option that recomputes cached results shortly before they expire,
+// with a probability that grows as the expiry gets closer. A beta of 1 is a good default,
+// larger values refresh earlier.
+func WithEarlyRefresh(beta float64) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.earlyRefreshBeta = beta
+	})
+}
@@ -0,0 +1,246 @@
+package rdbx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/go-redsync/redsync/v4"
+	"github.com/go-redsync/redsync/v4/redis/goredis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbx_QueryContext_stampede(t *testing.T) {
+	query := "SELECT id FROM users WHERE id = ?"
+
+	readAll := func(t *testing.T, rows *Rows) []int64 {
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			assert.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+
+		assert.NoError(t, rows.Err())
+		assert.NoError(t, rows.Close())
+
+		return ids
+	}
+
+	t.Run("concurrent misses run the query once", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		dbx := NewDbx(db, NewMemoryCache())
+
+		mock.ExpectQuery(query).
+			WithArgs(1).
+			WillDelayFor(100 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				rows, err := dbx.QueryContext(context.Background(), query, 1)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.Equal(t, []int64{1, 2}, readAll(t, rows))
+			}()
+		}
+
+		wg.Wait()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("followers query themselves when the leader stops early", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		dbx := NewDbx(db, NewMemoryCache())
+
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+		leader, err := dbx.QueryContext(context.Background(), query, 1)
+		assert.NoError(t, err)
+
+		done := make(chan []int64)
+		go func() {
+			rows, err := dbx.QueryContext(context.Background(), query, 1)
+			if !assert.NoError(t, err) {
+				close(done)
+				return
+			}
+
+			done <- readAll(t, rows)
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+
+		assert.True(t, leader.Next())
+		assert.NoError(t, leader.Close())
+
+		assert.Equal(t, []int64{1, 2}, <-done)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("followers stop waiting for a leader that takes too long", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		dbx := NewDbx(db, NewMemoryCache())
+		dbx.recomputeLockExpiry = 50 * time.Millisecond
+
+		mock.MatchExpectationsInOrder(false)
+		mock.ExpectQuery(query).WithArgs(1).WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			rows, err := dbx.QueryContext(context.Background(), query, 1)
+			if assert.NoError(t, err) {
+				readAll(t, rows)
+			}
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+
+		start := time.Now()
+		rows, err := dbx.QueryContext(context.Background(), query, 1)
+		assert.NoError(t, err)
+		assert.False(t, rows.Cached())
+		assert.Equal(t, []int64{1}, readAll(t, rows))
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+		<-done
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a query run again while its rows are open does not wait for them", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		dbx := NewDbx(db, NewMemoryCache())
+
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		first, err := dbx.QueryContext(context.Background(), query, 1)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		rows, err := dbx.QueryContext(ctx, query, 1)
+		assert.NoError(t, err)
+		assert.False(t, rows.Cached())
+		assert.Equal(t, []int64{1}, readAll(t, rows))
+		assert.Equal(t, []int64{1}, readAll(t, first))
+
+		rows, err = dbx.QueryContext(context.Background(), query, 1)
+		assert.NoError(t, err)
+		assert.True(t, rows.Cached())
+		assert.Equal(t, []int64{1}, readAll(t, rows))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("waits for the instance holding the recompute lock", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		redisClient, rMock := redismock.NewClientMock()
+		rsync := redsync.New(goredis.NewPool(redisClient))
+
+		c := NewMemoryCache()
+		dbx := NewDbx(db, c, WithRecomputeLock(rsync, time.Second))
+
+		key, err := dbx.keyBuilder.Key(query, 1)
+		assert.NoError(t, err)
+
+		rMock.Regexp().ExpectSetNX(key+":lock", ``, defaultRecomputeLockExpiry).SetVal(false)
+		rMock.Regexp().ExpectEvalSha(`.*`, []string{key + ":lock"}, ``).SetVal(int64(0))
+
+		other := NewDbx(db, c)
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+
+			rows, err := other.QueryContext(context.Background(), query, 1)
+			if assert.NoError(t, err) {
+				readAll(t, rows)
+			}
+		}()
+
+		rows, err := dbx.QueryContext(context.Background(), query, 1)
+		assert.NoError(t, err)
+		assert.True(t, rows.Cached())
+		assert.Equal(t, []int64{3}, readAll(t, rows))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("recomputes under the lock", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		assert.NoError(t, err)
+
+		redisClient, rMock := redismock.NewClientMock()
+		rsync := redsync.New(goredis.NewPool(redisClient))
+
+		dbx := NewDbx(db, NewMemoryCache(), WithRecomputeLock(rsync, time.Second))
+
+		key, err := dbx.keyBuilder.Key(query, 1)
+		assert.NoError(t, err)
+
+		rMock.Regexp().ExpectSetNX(key+":lock", ``, defaultRecomputeLockExpiry).SetVal(true)
+		rMock.Regexp().ExpectEvalSha(`.*`, []string{key + ":lock"}, ``).SetVal(int64(1))
+
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+		rows, err := dbx.QueryContext(context.Background(), query, 1)
+		assert.NoError(t, err)
+		assert.False(t, rows.Cached())
+		assert.Equal(t, []int64{4}, readAll(t, rows))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+}
+
+func Test_dbx_refreshEarly(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	dbx := NewDbx(nil, nil)
+	dbx.now = func() time.Time { return now }
+
+	entry := cacheEntry{delta: time.Second, expiresAt: now.Add(time.Hour)}
+
+	assert.False(t, dbx.refreshEarly(entry))
+
+	dbx.earlyRefreshBeta = 1
+	assert.False(t, dbx.refreshEarly(entry))
+	assert.True(t, dbx.refreshEarly(cacheEntry{delta: time.Second, expiresAt: now}))
+	assert.False(t, dbx.refreshEarly(cacheEntry{expiresAt: now}))
+
+	refreshed := 0
+	for i := 0; i < 1000; i++ {
+		if dbx.refreshEarly(cacheEntry{delta: time.Second, expiresAt: now.Add(time.Second)}) {
+			refreshed++
+		}
+	}
+
+	assert.Greater(t, refreshed, 200)
+	assert.Less(t, refreshed, 600)
+}
@@ -0,0 +1,310 @@
+package rdbx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Defaults of the two-tier cache.
+const (
+	defaultTieredCacheLocalTTL = 5 * time.Second
+	defaultTieredCacheChannel  = "rdbx:cache:invalidate"
+)
+
+// tieredCache is a Cache that keeps recently read values in process memory in front of Redis.
+// Local copies live for a short TTL, and every write or delete is published on a Redis channel
+// so the other instances drop their local copy right away.
+type tieredCache struct {
+	local  *memoryCache
+	remote *cache
+	client redis.UniversalClient
+
+	localTTL     time.Duration
+	localOptions []MemoryCacheOption
+	channel      string
+	id           string // Identifies this instance, so it ignores its own invalidations.
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// tieredInvalidation is the message published when keys are rewritten or deleted.
+type tieredInvalidation struct {
+	ID     string   `json:"id"`
+	Keys   []string `json:"keys,omitempty"`
+	Prefix string   `json:"prefix,omitempty"`
+}
+
+// NewTieredCache creates a two-tier Cache: an in-process memory cache in front of Redis.
+// It subscribes to the invalidation channel until Close is called.
+// Local copies may be stale for at most the local TTL when an invalidation message is lost,
+// e.g. while the subscription reconnects.
+func NewTieredCache(client redis.UniversalClient, options ...TieredCacheOption) *tieredCache {
+	c := newTieredCache(client, options...)
+	c.subscribe()
+
+	return c
+}
+
+// newTieredCache creates a two-tier cache without subscribing to invalidations.
+func newTieredCache(client redis.UniversalClient, options ...TieredCacheOption) *tieredCache {
+	c := &tieredCache{
+		remote:   NewRedisCache(client),
+		client:   client,
+		localTTL: defaultTieredCacheLocalTTL,
+		channel:  defaultTieredCacheChannel,
+		id:       newInstanceID(),
+		done:     make(chan struct{}),
+	}
+
+	for _, o := range options {
+		o.Apply(c)
+	}
+
+	c.local = NewMemoryCache(c.localOptions...)
+
+	return c
+}
+
+// Set stores the value in Redis and in the local tier, and invalidates the key on the other instances.
+func (c *tieredCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	c.publish(ctx, tieredInvalidation{Keys: []string{key}})
+
+	return c.local.Set(ctx, key, value, c.localTTLFor(ttl))
+}
+
+// Get returns the value from the local tier, or from Redis and keeps a local copy,
+// which never outlives the key in Redis.
+func (c *tieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if b, err := c.local.Get(ctx, key); err == nil {
+		return b, nil
+	}
+
+	b, ttl, err := c.remote.getWithTTL(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl < 0 {
+		return b, nil
+	}
+
+	if err := c.local.Set(ctx, key, b, c.localTTLFor(ttl)); err != nil {
+		log.Printf("cache local set error: %v", err)
+	}
+
+	return b, nil
+}
+
+// Append prepends the value to the list in Redis. Lists are not kept in the local tier.
+func (c *tieredCache) Append(ctx context.Context, key string, value interface{}) (int64, error) {
+	return c.remote.Append(ctx, key, value)
+}
+
+// GetList returns the list from Redis.
+func (c *tieredCache) GetList(ctx context.Context, key string) ([]string, error) {
+	return c.remote.GetList(ctx, key)
+}
+
+// Tag adds the key to the tag set in Redis. Tag sets are not kept in the local tier.
+func (c *tieredCache) Tag(ctx context.Context, tagKey, key string, ttl time.Duration) error {
+	return c.remote.Tag(ctx, tagKey, key, ttl)
+}
+
+// Delete removes the keys from Redis and from the local tier of every instance.
+func (c *tieredCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Redis first, so a concurrent Get that misses locally cannot copy the old value back.
+	if err := c.remote.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	if err := c.local.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	c.publish(ctx, tieredInvalidation{Keys: keys})
+
+	return nil
+}
+
+// DeleteByTag removes the tag set from Redis, and the keys it held from both tiers of every instance.
+func (c *tieredCache) DeleteByTag(ctx context.Context, tagKey string) error {
+	keys, err := c.remote.popTag(ctx, tagKey)
+	if err != nil {
+		return err
+	}
+
+	return c.Delete(ctx, keys...)
+}
+
+// DeleteByPrefix removes the keys starting with the prefix from both tiers of every instance,
+// from Redis first as Delete does.
+func (c *tieredCache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	if err := c.remote.DeleteByPrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	if err := c.local.DeleteByPrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	c.publish(ctx, tieredInvalidation{Prefix: prefix})
+
+	return nil
+}
+
+// TTL returns the time to live of the key in Redis.
+func (c *tieredCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.remote.TTL(ctx, key)
+}
+
+// Stats returns the statistics of Redis, with the hits served by the local tier added to the hits.
+func (c *tieredCache) Stats(ctx context.Context) (CacheStats, error) {
+	local, err := c.local.Stats(ctx)
+	if err != nil {
+		return CacheStats{}, err
+	}
+
+	stats, err := c.remote.Stats(ctx)
+	stats.Hits += local.Hits
+
+	return stats, err
+}
+
+// Close stops listening for invalidations.
+func (c *tieredCache) Close() error {
+	if c.pubsub == nil {
+		return nil
+	}
+
+	err := c.pubsub.Close()
+	<-c.done
+
+	return err
+}
+
+// localTTLFor returns how long a value that lives for ttl in Redis, zero meaning forever,
+// is kept in the local tier. It is never zero, which would keep the local copy forever.
+func (c *tieredCache) localTTLFor(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < c.localTTL {
+		return ttl
+	}
+
+	return c.localTTL
+}
+
+// publish tells the other instances to drop their local copy of the keys of the message.
+// A failed publish is logged, the local copies then expire after the local TTL.
+func (c *tieredCache) publish(ctx context.Context, inv tieredInvalidation) {
+	inv.ID = c.id
+
+	msg, err := json.Marshal(inv)
+	if err != nil {
+		log.Printf("cache invalidation encode error: %v", err)
+		return
+	}
+
+	if err := c.client.Publish(ctx, c.channel, msg).Err(); err != nil {
+		log.Printf("cache invalidation publish error: %v", err)
+	}
+}
+
+// subscribe listens for invalidations published by the other instances.
+func (c *tieredCache) subscribe() {
+	c.pubsub = c.client.Subscribe(context.Background(), c.channel)
+
+	go func() {
+		defer close(c.done)
+
+		for msg := range c.pubsub.Channel() {
+			c.handleInvalidation(msg.Payload)
+		}
+	}()
+}
+
+// handleInvalidation drops the local copies of the keys, or of the prefix, of an invalidation message.
+func (c *tieredCache) handleInvalidation(payload string) {
+	var msg tieredInvalidation
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("cache invalidation decode error: %v", err)
+		return
+	}
+
+	if msg.ID == c.id {
+		return
+	}
+
+	if err := c.local.Delete(context.Background(), msg.Keys...); err != nil {
+		log.Printf("cache invalidation error: %v", err)
+	}
+
+	if msg.Prefix == "" {
+		return
+	}
+
+	if err := c.local.DeleteByPrefix(context.Background(), msg.Prefix); err != nil {
+		log.Printf("cache invalidation error: %v", err)
+	}
+}
+
+// newInstanceID returns a random identifier for this process.
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// TieredCacheOption represents an option for the two-tier cache.
+type TieredCacheOption interface {
+	Apply(*tieredCache)
+}
+
+// tieredCacheOptionFunc represents a function that applies an option to the two-tier cache.
+type tieredCacheOptionFunc func(*tieredCache)
+
+// Apply applies the option to the two-tier cache.
+func (f tieredCacheOptionFunc) Apply(c *tieredCache) {
+	f(c)
+}
+
+// WithLocalTTL returns an option that sets how long values are kept in the local tier, 5 seconds by default.
+// Local copies must expire, so a ttl of zero or less is ignored.
+func WithLocalTTL(ttl time.Duration) TieredCacheOption {
+	return tieredCacheOptionFunc(func(c *tieredCache) {
+		if ttl > 0 {
+			c.localTTL = ttl
+		}
+	})
+}
+
+// WithLocalCacheOptions returns an option that configures the bounds of the local tier.
+func WithLocalCacheOptions(options ...MemoryCacheOption) TieredCacheOption {
+	return tieredCacheOptionFunc(func(c *tieredCache) {
+		c.localOptions = append(c.localOptions, options...)
+	})
+}
+
+// WithInvalidationChannel returns an option that sets the Redis channel invalidations are published on.
+// Every instance sharing the cache must use the same channel.
+func WithInvalidationChannel(channel string) TieredCacheOption {
+	return tieredCacheOptionFunc(func(c *tieredCache) {
+		c.channel = channel
+	})
+}
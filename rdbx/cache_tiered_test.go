@@ -0,0 +1,185 @@
+package rdbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_tieredCache(t *testing.T) {
+	ctx := context.Background()
+
+	redisClient, rMock := redismock.NewClientMock()
+
+	newCache := func() *tieredCache {
+		rMock.ClearExpect()
+
+		c := newTieredCache(redisClient, WithLocalTTL(time.Minute), WithLocalCacheOptions(WithMaxEntries(10)))
+		c.id = "instance-1"
+
+		return c
+	}
+
+	t.Run("get keeps a local copy", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectGet("a").SetVal("1")
+		rMock.ExpectPTTL("a").SetVal(-1)
+
+		for i := 0; i < 3; i++ {
+			b, err := c.Get(ctx, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("1"), b)
+		}
+
+		rMock.ExpectGet("b").SetErr(redis.Nil)
+
+		_, err := c.Get(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("local copies do not outlive the key in Redis", func(t *testing.T) {
+		now := time.Now()
+
+		c := newCache()
+		c.local.now = func() time.Time { return now }
+
+		rMock.ExpectGet("a").SetVal("1")
+		rMock.ExpectPTTL("a").SetVal(2 * time.Second)
+
+		_, err := c.Get(ctx, "a")
+		assert.NoError(t, err)
+
+		ttl, err := c.local.TTL(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, 2*time.Second, ttl)
+
+		now = now.Add(2 * time.Second)
+
+		rMock.ExpectGet("a").SetErr(redis.Nil)
+
+		_, err = c.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("set writes both tiers and publishes the key", func(t *testing.T) {
+		c := newCache()
+
+		rMock.ExpectSet("a", []byte("1"), 30*time.Second).SetVal("OK")
+		rMock.ExpectPublish(defaultTieredCacheChannel, []byte(`{"id":"instance-1","keys":["a"]}`)).SetVal(1)
+
+		assert.NoError(t, c.Set(ctx, "a", []byte("1"), 30*time.Second))
+
+		b, err := c.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("1"), b)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("delete removes both tiers and publishes the keys", func(t *testing.T) {
+		c := newCache()
+
+		assert.NoError(t, c.local.Set(ctx, "a", "1", 0))
+
+		rMock.ExpectDel("a").SetVal(1)
+		rMock.ExpectDel("b").SetVal(0)
+		rMock.ExpectPublish(defaultTieredCacheChannel, []byte(`{"id":"instance-1","keys":["a","b"]}`)).SetVal(1)
+		rMock.ExpectGet("a").SetErr(redis.Nil)
+
+		assert.NoError(t, c.Delete(ctx, "a", "b"))
+
+		_, err := c.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("delete keeps the local copy when Redis fails", func(t *testing.T) {
+		c := newCache()
+
+		assert.NoError(t, c.local.Set(ctx, "a", "1", 0))
+
+		rMock.ExpectDel("a").SetErr(errors.New("redis down"))
+
+		assert.Error(t, c.Delete(ctx, "a"))
+
+		b, err := c.local.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("1"), b)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("delete by tag removes the tagged keys from both tiers", func(t *testing.T) {
+		c := newCache()
+
+		assert.NoError(t, c.local.Set(ctx, "rdbx:a", "1", 0))
+
+		rMock.ExpectEvalSha(popTagScript.Hash(), []string{"rdbx:tag:users"}).SetVal([]interface{}{"rdbx:a"})
+		rMock.ExpectDel("rdbx:a").SetVal(1)
+		rMock.ExpectPublish(defaultTieredCacheChannel, []byte(`{"id":"instance-1","keys":["rdbx:a"]}`)).SetVal(1)
+
+		assert.NoError(t, c.DeleteByTag(ctx, "rdbx:tag:users"))
+
+		_, err := c.local.Get(ctx, "rdbx:a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("invalidations from other instances drop local copies", func(t *testing.T) {
+		c := newCache()
+
+		assert.NoError(t, c.local.Set(ctx, "a", "1", 0))
+		assert.NoError(t, c.local.Set(ctx, "b", "2", 0))
+
+		c.handleInvalidation(`{"id":"instance-1","keys":["a"]}`)
+		c.handleInvalidation(`{"id":"instance-2","keys":["b"]}`)
+		c.handleInvalidation(`not json`)
+
+		_, err := c.local.Get(ctx, "a")
+		assert.NoError(t, err)
+
+		_, err = c.local.Get(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("delete by prefix removes both tiers and publishes the prefix", func(t *testing.T) {
+		c := newCache()
+
+		assert.NoError(t, c.local.Set(ctx, "rdbx:a", "1", 0))
+
+		rMock.ExpectScan(0, "rdbx:*", redisScanCount).SetVal([]string{"rdbx:a"}, 0)
+		rMock.ExpectDel("rdbx:a").SetVal(1)
+		rMock.ExpectPublish(defaultTieredCacheChannel, []byte(`{"id":"instance-1","prefix":"rdbx:"}`)).SetVal(1)
+
+		assert.NoError(t, c.DeleteByPrefix(ctx, "rdbx:"))
+
+		_, err := c.local.Get(ctx, "rdbx:a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NoError(t, rMock.ExpectationsWereMet())
+
+		assert.NoError(t, c.local.Set(ctx, "rdbx:b", "2", 0))
+		c.handleInvalidation(`{"id":"instance-2","prefix":"rdbx:"}`)
+
+		_, err = c.local.Get(ctx, "rdbx:b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("local ttl", func(t *testing.T) {
+		c := newCache()
+
+		assert.Equal(t, time.Second, c.localTTLFor(time.Second))
+		assert.Equal(t, time.Minute, c.localTTLFor(time.Hour))
+		assert.Equal(t, time.Minute, c.localTTLFor(0))
+
+		for _, ttl := range []time.Duration{0, -time.Second} {
+			c := newTieredCache(redisClient, WithLocalTTL(ttl))
+			assert.Equal(t, defaultTieredCacheLocalTTL, c.localTTLFor(0))
+		}
+	})
+}
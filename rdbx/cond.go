@@ -0,0 +1,113 @@
+package rdbx
+
+import "strings"
+
+// Cond is a condition of a WHERE clause, built with Eq, Ne, Lt, Le, Gt, Ge, In and Like,
+// and combined with And and Or.
+// The column is written to the query as is: it must never come from user input.
+type Cond struct {
+	sql  string
+	args []interface{}
+}
+
+// compare returns the condition "column op ?".
+func compare(column, op string, value interface{}) Cond {
+	return Cond{sql: column + " " + op + " ?", args: []interface{}{value}}
+}
+
+// Eq returns the condition column = value, or column IS NULL for a nil value.
+func Eq(column string, value interface{}) Cond {
+	if value == nil {
+		return Cond{sql: column + " IS NULL"}
+	}
+
+	return compare(column, "=", value)
+}
+
+// Ne returns the condition column <> value, or column IS NOT NULL for a nil value.
+func Ne(column string, value interface{}) Cond {
+	if value == nil {
+		return Cond{sql: column + " IS NOT NULL"}
+	}
+
+	return compare(column, "<>", value)
+}
+
+// Lt returns the condition column < value.
+func Lt(column string, value interface{}) Cond {
+	return compare(column, "<", value)
+}
+
+// Le returns the condition column <= value.
+func Le(column string, value interface{}) Cond {
+	return compare(column, "<=", value)
+}
+
+// Gt returns the condition column > value.
+func Gt(column string, value interface{}) Cond {
+	return compare(column, ">", value)
+}
+
+// Ge returns the condition column >= value.
+func Ge(column string, value interface{}) Cond {
+	return compare(column, ">=", value)
+}
+
+// In returns the condition column IN (values), values being a slice. An empty slice matches no row.
+func In(column string, values interface{}) Cond {
+	return Cond{sql: column + " IN (?)", args: []interface{}{values}}
+}
+
+// whereClause returns the WHERE clause that joins the conditions with AND, and its arguments.
+// It returns an empty clause without conditions.
+func whereClause(conds []Cond) (string, []interface{}) {
+	if len(conds) == 0 {
+		return "", nil
+	}
+
+	sql, args := joinConds(conds, " AND ")
+
+	return " WHERE " + sql, args
+}
+
+// Like returns the condition column LIKE pattern.
+func Like(column, pattern string) Cond {
+	return compare(column, "LIKE", pattern)
+}
+
+// And returns the condition that matches when every condition does, or every row without conditions.
+func And(conds ...Cond) Cond {
+	return join(conds, " AND ", "1 = 1")
+}
+
+// Or returns the condition that matches when any condition does, or no row without conditions.
+func Or(conds ...Cond) Cond {
+	return join(conds, " OR ", "1 = 0")
+}
+
+// join returns the conditions joined with the operator in parentheses, or empty without conditions.
+func join(conds []Cond, op, empty string) Cond {
+	switch len(conds) {
+	case 0:
+		return Cond{sql: empty}
+	case 1:
+		return conds[0]
+	}
+
+	sql, args := joinConds(conds, op)
+
+	return Cond{sql: "(" + sql + ")", args: args}
+}
+
+// joinConds joins the conditions with the operator, and returns their arguments.
+func joinConds(conds []Cond, op string) (string, []interface{}) {
+	parts := make([]string, len(conds))
+
+	var args []interface{}
+	for i, c := range conds {
+		parts[i] = c.sql
+		args = append(args, c.args...)
+	}
+
+	return strings.Join(parts, op), args
+}
@@ -0,0 +1,210 @@
+package rdbx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// errNullValue is returned when a NULL is scanned into a field that cannot hold it.
+var errNullValue = errors.New("NULL cannot be assigned, use a pointer or sql.Null type, or enable WithZeroOnNull")
+
+// timeType is the type of time.Time.
+var timeType = reflect.TypeOf(time.Time{})
+
+// convertAssign assigns a non-NULL column value to a field that is not a pointer nor a sql.Scanner,
+// converting it the way database/sql converts values for Scan. Bytes kept by the field are copied,
+// the driver may reuse them for the next row.
+func convertAssign(dst reflect.Value, src interface{}) error {
+	if b, ok := src.([]byte); ok {
+		switch dst.Kind() {
+		case reflect.Slice:
+			src = append([]byte{}, b...)
+		case reflect.Interface:
+			src = string(b)
+		}
+	}
+
+	sv := reflect.ValueOf(src)
+
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			dst.SetString(s)
+		case []byte:
+			dst.SetString(string(s))
+		case time.Time:
+			dst.SetString(s.Format(time.RFC3339Nano))
+		default:
+			if !isNumberKind(sv.Kind()) && sv.Kind() != reflect.Bool {
+				return cannotConvert(src, dst)
+			}
+
+			dst.SetString(fmt.Sprint(src))
+		}
+
+		return nil
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+
+		switch s := src.(type) {
+		case string:
+			dst.SetBytes([]byte(s))
+			return nil
+		case []byte:
+			dst.SetBytes(s)
+			return nil
+		}
+	case reflect.Bool:
+		b, err := driver.Bool.ConvertValue(src)
+		if err != nil {
+			return err
+		}
+
+		dst.SetBool(b.(bool))
+
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i := sv.Int()
+			if dst.OverflowInt(i) {
+				return overflows(src, dst)
+			}
+
+			dst.SetInt(i)
+
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u := sv.Uint()
+			if u > math.MaxInt64 || dst.OverflowInt(int64(u)) {
+				return overflows(src, dst)
+			}
+
+			dst.SetInt(int64(u))
+
+			return nil
+		}
+
+		s, ok := asString(src)
+		if !ok {
+			break
+		}
+
+		i, err := strconv.ParseInt(s, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		dst.SetInt(i)
+
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i := sv.Int()
+			if i < 0 || dst.OverflowUint(uint64(i)) {
+				return overflows(src, dst)
+			}
+
+			dst.SetUint(uint64(i))
+
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u := sv.Uint()
+			if dst.OverflowUint(u) {
+				return overflows(src, dst)
+			}
+
+			dst.SetUint(u)
+
+			return nil
+		}
+
+		s, ok := asString(src)
+		if !ok {
+			break
+		}
+
+		u, err := strconv.ParseUint(s, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		dst.SetUint(u)
+
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if isNumberKind(sv.Kind()) {
+			dst.SetFloat(sv.Convert(dst.Type()).Float())
+			return nil
+		}
+
+		s, ok := asString(src)
+		if !ok {
+			break
+		}
+
+		f, err := strconv.ParseFloat(s, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		dst.SetFloat(f)
+
+		return nil
+	}
+
+	if dst.Type() != timeType && sv.Type().ConvertibleTo(dst.Type()) && sv.Kind() == dst.Kind() {
+		dst.Set(sv.Convert(dst.Type()))
+		return nil
+	}
+
+	return cannotConvert(src, dst)
+}
+
+// asString returns the text of a string or []byte value.
+func asString(src interface{}) (string, bool) {
+	switch s := src.(type) {
+	case string:
+		return s, true
+	case []byte:
+		return string(s), true
+	}
+
+	return "", false
+}
+
+// isNumberKind reports whether the kind is an integer or floating point number.
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// cannotConvert returns the error of a value that cannot be assigned to the field.
+func cannotConvert(src interface{}, dst reflect.Value) error {
+	return fmt.Errorf("cannot convert %T to %s", src, dst.Type())
+}
+
+// overflows returns the error of an integer that does not fit the field.
+func overflows(src interface{}, dst reflect.Value) error {
+	return fmt.Errorf("value %v overflows %s", src, dst.Type())
+}
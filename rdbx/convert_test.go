@@ -0,0 +1,78 @@
+package rdbx
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_convertAssign(t *testing.T) {
+	type status string
+
+	createdAt := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		dst     interface{}
+		src     interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "int64", dst: new(int64), src: int64(42), want: int64(42)},
+		{name: "int64 into int32", dst: new(int32), src: int64(42), want: int32(42)},
+		{name: "int64 overflows int8", dst: new(int8), src: int64(300), wantErr: true},
+		{name: "text int", dst: new(int), src: []byte("42"), want: 42},
+		{name: "text uint", dst: new(uint16), src: "42", want: uint16(42)},
+		{name: "negative into uint", dst: new(uint), src: int64(-1), wantErr: true},
+		{name: "uint64 into int64", dst: new(int64), src: uint64(42), want: int64(42)},
+		{name: "uint64 overflows int64", dst: new(int64), src: uint64(math.MaxUint64), wantErr: true},
+		{name: "int64 into uint8", dst: new(uint8), src: int64(255), want: uint8(255)},
+		{name: "int64 overflows uint8", dst: new(uint8), src: int64(256), wantErr: true},
+		{name: "text float", dst: new(float64), src: []byte("1.5"), want: 1.5},
+		{name: "int into float", dst: new(float32), src: int64(2), want: float32(2)},
+		{name: "float into int", dst: new(int64), src: 1.5, wantErr: true},
+		{name: "string", dst: new(string), src: "alie", want: "alie"},
+		{name: "bytes into string", dst: new(string), src: []byte("alie"), want: "alie"},
+		{name: "int into string", dst: new(string), src: int64(65), want: "65"},
+		{name: "named string", dst: new(status), src: "active", want: status("active")},
+		{name: "string into bytes", dst: new([]byte), src: "alie", want: []byte("alie")},
+		{name: "int into bool", dst: new(bool), src: int64(1), want: true},
+		{name: "text bool", dst: new(bool), src: "false", want: false},
+		{name: "invalid bool", dst: new(bool), src: int64(2), wantErr: true},
+		{name: "time", dst: new(time.Time), src: createdAt, want: createdAt},
+		{name: "text into time", dst: new(time.Time), src: "2023-05-01", wantErr: true},
+		{name: "text into int", dst: new(int64), src: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := reflect.ValueOf(tt.dst).Elem()
+
+			err := convertAssign(dst, tt.src)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, dst.Interface())
+		})
+	}
+}
+
+func Benchmark_convertAssign(b *testing.B) {
+	var id int32
+	dst := reflect.ValueOf(&id).Elem()
+
+	var src interface{} = int64(42)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := convertAssign(dst, src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package rdbx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// Cursor streams the rows of a query as values of T, a struct with column tags, one row at a time.
+// It suits exports and batch jobs that walk more rows than fit in memory: rows are never cached.
+//
+//	cur, err := rdbx.OpenCursor[User](ctx, db, "SELECT * FROM users")
+//	if err != nil {
+//		return err
+//	}
+//	defer cur.Close()
+//
+//	for cur.Next() {
+//		u := cur.Value()
+//		...
+//	}
+//
+//	return cur.Err()
+type Cursor[T any] struct {
+	ctx  context.Context
+	rows *Rows
+	sc   *structScanner
+
+	value T
+	err   error
+}
+
+// OpenCursor runs the query and returns a Cursor over its rows. A "*" in the query is expanded to
+// the columns of T, as with Queryx. The query always reads from the database, and the cursor stops
+// with the context error as soon as the context is done.
+func OpenCursor[T any](ctx context.Context, db DBTX, query string, args ...interface{}) (*Cursor[T], error) {
+	var zero T
+
+	elType := reflect.TypeOf(zero)
+	if elType == nil || elType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rdbx: cannot scan rows into %T, want a struct", zero)
+	}
+
+	query = internal.ModifyOrKeepField(query, zero)
+
+	rows, err := db.QueryContext(NoCache(ctx), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts scanOptions
+	if c, ok := db.(scanConfigurer); ok {
+		opts = c.scanConfig()
+	}
+
+	sc, err := newStructScanner(rows, elType, opts)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+
+	return &Cursor[T]{ctx: ctx, rows: rows, sc: sc}, nil
+}
+
+// Next scans the next row. It returns false at the end of the rows, when the context is done,
+// or on error, which Err then reports.
+func (c *Cursor[T]) Next() bool {
+	if c.err != nil {
+		return false
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+
+	if !c.rows.Next() {
+		c.err = c.rows.Err()
+		return false
+	}
+
+	var value T
+	if err := c.sc.scan(c.rows, reflect.ValueOf(&value).Elem()); err != nil {
+		c.err = err
+		return false
+	}
+
+	c.value = value
+
+	return true
+}
+
+// Value returns the row scanned by the last call to Next.
+func (c *Cursor[T]) Value() T {
+	return c.value
+}
+
+// Err returns the error that stopped the cursor, if any.
+func (c *Cursor[T]) Err() error {
+	return c.err
+}
+
+// Close closes the rows. It is safe to call Close more than once.
+func (c *Cursor[T]) Close() error {
+	return c.rows.Close()
+}
+
+// QueryEach runs the query and calls fn with every row, as a T, until the rows are exhausted,
+// the context is done or fn returns an error, which QueryEach then returns.
+// Like a Cursor, it never caches the rows.
+func QueryEach[T any](ctx context.Context, db DBTX, query string, fn func(T) error, args ...interface{}) error {
+	cur, err := OpenCursor[T](ctx, db, query, args...)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+
+	for cur.Next() {
+		if err := fn(cur.Value()); err != nil {
+			return err
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	return cur.Close()
+}
@@ -0,0 +1,90 @@
+package rdbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID int64 `column:"id"`
+	}
+
+	query := "SELECT id FROM users"
+
+	expectQuery := func() {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+	}
+
+	c := newMapCache()
+	dbx := NewDbx(db, c)
+
+	t.Run("streams every row without caching", func(t *testing.T) {
+		expectQuery()
+
+		cur, err := OpenCursor[user](context.Background(), dbx, "SELECT * FROM users")
+		assert.NoError(t, err)
+
+		var got []user
+		for cur.Next() {
+			got = append(got, cur.Value())
+		}
+
+		assert.NoError(t, cur.Err())
+		assert.NoError(t, cur.Close())
+		assert.NoError(t, cur.Close())
+		assert.Equal(t, []user{{ID: 1}, {ID: 2}, {ID: 3}}, got)
+		assert.Empty(t, c.values)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		expectQuery()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var got []user
+		err := QueryEach(ctx, dbx, query, func(u user) error {
+			got = append(got, u)
+			if u.ID == 2 {
+				cancel()
+			}
+
+			return nil
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, []user{{ID: 1}, {ID: 2}}, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops on the first callback error", func(t *testing.T) {
+		expectQuery()
+
+		errStop := errors.New("stop")
+
+		var calls int
+		err := QueryEach(context.Background(), dbx, query, func(u user) error {
+			calls++
+			return errStop
+		})
+
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, calls)
+		assert.Empty(t, c.values)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects non struct types", func(t *testing.T) {
+		_, err := OpenCursor[int64](context.Background(), dbx, query)
+		assert.Error(t, err)
+	})
+}
@@ -1,22 +1,23 @@
 package rdbx
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/hex"
 	"errors"
-	"log"
 	"reflect"
+	"time"
 
 	"github.com/farislr/commoneer/rdbx/internal"
+	"github.com/go-redsync/redsync/v4"
 )
 
 // contextKeyEnableSqlTx is a context key used to enable SQL transactions.
 type contextKeyEnableSqlTx struct{}
 
 // Queryx executes a query that returns rows, typically a SELECT, and maps the result to a struct or slice of structs.
-// The model parameter should be a pointer to a struct or slice of structs.
+// The model parameter should be a pointer to a struct or slice of structs. It can also point to a map[string]interface{}
+// or a slice of them, keyed by column, or for a query of a single column to a scalar such as an int64 for
+// SELECT COUNT(*), or a slice of scalars such as []string. A struct, map or scalar receives the last row.
 // The query parameter can contain placeholders for arguments.
 // The args parameter is a list of arguments to replace the placeholders in the query.
 func (db *dbx) Queryx(
@@ -33,7 +34,15 @@ func (db *dbx) Queryx(
 		return errors.New("model should be pointer, pointer struct, or pointer slice struct")
 	}
 
-	query = internal.ModifyOrKeepField(query, model)
+	elType := t
+	if t.Kind() == reflect.Slice && !isScalar(t) {
+		elType = t.Elem()
+	}
+
+	// Only the columns of a struct are known, a "*" is kept for maps and scalars.
+	if elType.Kind() == reflect.Struct && !isScalar(elType) {
+		query = internal.ModifyOrKeepField(query, model)
+	}
 
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -42,122 +51,34 @@ func (db *dbx) Queryx(
 	}
 	defer rows.Close()
 
-	err = db.rowScan(rows, p, t)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// rowScan scans the rows returned by a query and maps the result to a struct or slice of structs.
-func (db *dbx) rowScan(rows *Rows, rVal reflect.Value, rType reflect.Type) error {
-	el := rVal
-
-	if rVal.Kind() == reflect.Slice {
-		el = reflect.Indirect(reflect.New(rVal.Type().Elem()))
-		rType = el.Type()
-	}
-
-	columns, err := rows.Columns()
+	err = scanRows(rows, p, db.scanConfig())
 	if err != nil {
 		return err
 	}
 
-	count := len(columns)
-	vs := make([]interface{}, count)
-	vPtrs := make([]interface{}, count)
-
-	for rows.Next() {
-		for i := range columns {
-			vPtrs[i] = &vs[i]
-		}
-
-		if err := rows.Scan(vPtrs...); err != nil {
-			return err
-		}
-
-		if err := db.assignField(rType, el, columns, vs); err != nil {
-			return err
-		}
-
-		if rVal.Kind() == reflect.Slice {
-			rVal.Set(reflect.Append(rVal, el))
-		}
-	}
-
-	return nil
-}
-
-// assignField assigns a value to a field in a struct based on the column name in the result set.
-func (db *dbx) assignField(
-	rType reflect.Type,
-	rValue reflect.Value,
-	columns []string,
-	values []interface{},
-) error {
-	for i := 0; i < rType.NumField(); i++ {
-		if c, ok := rType.Field(i).Tag.Lookup("column"); ok {
-			for ii, col := range columns {
-				if col == c {
-					colVal := values[ii]
-
-					if b, ok := colVal.([]byte); ok {
-						colVal = string(b)
-					}
-
-					if ok := db.checkStructFieldSetable(rValue); ok {
-						if err := db.checkStructFieldType(rValue.Field(i), colVal); err != nil {
-							return err
-						}
-					}
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
-// checkStructFieldType checks the type of a struct field and assigns a value to it.
-func (db *dbx) checkStructFieldType(element reflect.Value, value interface{}) error {
-	switch t := element.Addr().Interface().(type) {
-	case sql.Scanner:
-		if err := db.sqlScannerSet(element, t, value); err != nil {
-			return err
-		}
-	default:
-		element.Set(reflect.ValueOf(value).Convert(element.Type()))
-	}
-
-	return nil
-}
-
-// sqlScannerSet sets a value for a struct field that implements the sql.Scanner interface.
-func (dbx *dbx) sqlScannerSet(element reflect.Value, t sql.Scanner, value interface{}) error {
-	if err := t.Scan(value); err != nil {
-		return err
-	}
-
-	element.Set(reflect.ValueOf(t).Elem())
-
 	return nil
 }
 
-// checkStructFieldSetable checks if a struct field can be set.
-func (dbx *dbx) checkStructFieldSetable(element reflect.Value) bool {
-	if element.IsValid() && element.CanSet() && element.CanAddr() {
-		return true
-	}
-
-	return false
-}
-
 // dbx is a wrapper around sql.DB that provides additional functionality.
 type dbx struct {
 	db *sql.DB
 
-	cache Cache
+	cache      Cache
+	cacheTTL   time.Duration
+	keyBuilder KeyBuilder
+	encoding   resultEncoding
+
+	flights             flightGroup[string]
+	generations         tagGenerations
+	rsync               *redsync.Redsync
+	recomputeWait       time.Duration
+	recomputeLockExpiry time.Duration
+	earlyRefreshBeta    float64
+
+	zeroOnNull bool
+	dialect    Dialect
+
+	now func() time.Time
 }
 
 // Begin starts a new transaction.
@@ -172,21 +93,43 @@ func (x *dbx) Close() error {
 
 // ExecContext executes a query that does not return rows, such as an INSERT or UPDATE.
 // The query parameter can contain placeholders for arguments.
-// The args parameter is a list of arguments to replace the placeholders in the query.
+// The args parameter is a list of arguments to replace the placeholders in the query,
+// the slice argument of an "id IN (?)" list is expanded to one placeholder per element.
+// Cached results of the tables the query writes to, and of the tags set with WithCacheTags, are invalidated.
+// Inside a transaction the invalidation happens once the transaction is committed.
+// A query of this dbx that read the old rows and is still running is not cached afterwards, but
+// one of another process may still cache the old rows, for at most the cache TTL.
 func (x *dbx) ExecContext(
 	ctx context.Context,
 	query string,
 	args ...interface{},
 ) (sql.Result, error) {
+	query, args, err := x.bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var res sql.Result
+
 	if tx, ok := ctx.Value(&contextKeyEnableSqlTx{}).(*sql.Tx); ok {
-		return tx.ExecContext(ctx, query, args...)
+		res, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		res, err = x.db.ExecContext(ctx, query, args...)
 	}
 
-	return x.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	x.invalidate(ctx, query)
+
+	return res, nil
 }
 
 // PrepareContext prepares a statement for execution.
 func (x *dbx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	query = x.dialect.Rebind(query)
+
 	if tx, ok := ctx.Value(&contextKeyEnableSqlTx{}).(*sql.Tx); ok {
 		return tx.PrepareContext(ctx, query)
 	}
@@ -196,49 +139,64 @@ func (x *dbx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, erro
 
 // QueryContext executes a query that returns rows, typically a SELECT.
 // The query parameter can contain placeholders for arguments.
-// The args parameter is a list of arguments to replace the placeholders in the query.
+// The args parameter is a list of arguments to replace the placeholders in the query,
+// the slice argument of an "id IN (?)" list is expanded to one placeholder per element.
 // Returns a Rows object that wraps the result set.
-// If the query has been executed before and the result set is cached, the cached result set will be returned.
+// If the query has been executed before and the result set is cached, the cached result set will be returned
+// without touching the database. Otherwise the result set is cached once it has been fully read and closed.
+// Caching can be tuned per call with WithCacheTTL, NoCache, RefreshCache and WithCacheTags.
+// Queries inside a transaction started by EnableTx always read from the database and are not cached.
+// Concurrent cache misses of the same query in this process run the query only once.
 func (x *dbx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
-	queryKey := hex.EncodeToString([]byte(query))
+	query, args, err := x.bind(query, args)
+	if err != nil {
+		return nil, err
+	}
 
-	var rows *sql.Rows
-	var err error
+	target, mode := x.cacheTarget(ctx, query, args...)
+	if target != nil {
+		return x.cachedQuery(ctx, target, mode, query, args...)
+	}
 
-	res, err := x.cache.Get(ctx, string(queryKey))
+	rows, err := x.query(ctx, query, args...)
 	if err != nil {
-		log.Printf("cache get error: %v", err)
+		return nil, err
 	}
 
-	if tx, ok := ctx.Value(&contextKeyEnableSqlTx{}).(*sql.Tx); ok {
-		rows, err = tx.QueryContext(ctx, query, args...)
-		if err != nil {
-			return nil, err
-		}
+	return newRows(ctx, rows, nil), nil
+}
 
-		goto ReturnRows
+// query runs the query in the transaction of the context, if any, or on the database.
+func (x *dbx) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx, ok := ctx.Value(&contextKeyEnableSqlTx{}).(*sql.Tx); ok {
+		return tx.QueryContext(ctx, query, args...)
 	}
 
-	rows, err = x.db.QueryContext(ctx, query, args...)
+	return x.db.QueryContext(ctx, query, args...)
+}
+
+// bind expands the slice arguments of the query and rebinds its placeholders to the dialect.
+func (x *dbx) bind(query string, args []interface{}) (string, []interface{}, error) {
+	query, args, err := internal.ExpandIn(query, args, x.dialect.syntax())
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-ReturnRows:
-	return &Rows{
-		ctx:        ctx,
-		Rows:       rows,
-		cachedRows: bytes.NewBuffer(res),
-		cache:      x.cache,
-		queryKey:   queryKey,
-	}, nil
+	return x.dialect.Rebind(query), args, nil
 }
 
 // QueryRowContext executes a query that is expected to return at most one row.
 // The query parameter can contain placeholders for arguments.
-// The args parameter is a list of arguments to replace the placeholders in the query.
+// The args parameter is a list of arguments to replace the placeholders in the query,
+// the slice argument of an "id IN (?)" list is expanded to one placeholder per element.
 // Returns a Row object that wraps the result.
 func (x *dbx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	// A Row cannot carry an error of its own: a query that cannot be bound is sent as is,
+	// and the driver reports the mismatch when the row is scanned.
+	if q, a, err := x.bind(query, args); err == nil {
+		query, args = q, a
+	}
+
 	if tx, ok := ctx.Value(&contextKeyEnableSqlTx{}).(*sql.Tx); ok {
 		return tx.QueryRowContext(ctx, query, args...)
 	}
@@ -247,9 +205,66 @@ func (x *dbx) QueryRowContext(ctx context.Context, query string, args ...interfa
 }
 
 // NewDbx creates a new dbx object.
-func NewDbx(db *sql.DB, cache Cache) *dbx {
-	return &dbx{
-		db:    db,
-		cache: cache,
+// Query results are cached in the given cache, a nil cache disables caching.
+func NewDbx(db *sql.DB, cache Cache, options ...DbxOption) *dbx {
+	x := &dbx{
+		db:         db,
+		cache:      cache,
+		cacheTTL:   defaultCacheTTL,
+		keyBuilder: NewHashKeyBuilder("", ""),
+		encoding:   resultEncoding{codec: BinaryRowCodec},
+
+		recomputeLockExpiry: defaultRecomputeLockExpiry,
+
+		now: time.Now,
 	}
+
+	for _, o := range options {
+		o.Apply(x)
+	}
+
+	return x
+}
+
+// DbxOption represents an option for the dbx object.
+type DbxOption interface {
+	Apply(*dbx)
+}
+
+// dbxOptionFunc represents a function that applies an option to the dbx object.
+type dbxOptionFunc func(*dbx)
+
+// Apply applies the option to the dbx object.
+func (f dbxOptionFunc) Apply(x *dbx) {
+	f(x)
+}
+
+// WithKeyBuilder returns an option that sets how cache keys are built from queries and their arguments.
+func WithKeyBuilder(kb KeyBuilder) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.keyBuilder = kb
+	})
+}
+
+// WithDefaultCacheTTL returns an option that sets how long query results are cached
+// when the context does not set a TTL with WithCacheTTL. A ttl of zero or less disables caching by default.
+func WithDefaultCacheTTL(ttl time.Duration) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.cacheTTL = ttl
+	})
+}
+
+// WithZeroOnNull returns an option that makes Queryx set the fields of NULL columns to their zero value,
+// instead of returning an error for fields that are neither pointers nor sql.Scanner.
+// A single field opts in with the zeronull tag option, e.g. `column:"nickname,zeronull"`.
+func WithZeroOnNull() DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.zeroOnNull = true
+	})
+}
+
+// WithCacheNamespace returns an option that prefixes cache keys with the namespace and version,
+// so several services can share one cache, and a version bump discards every cached result.
+func WithCacheNamespace(namespace, version string) DbxOption {
+	return WithKeyBuilder(NewHashKeyBuilder(namespace, version))
 }
@@ -0,0 +1,191 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// cacheTarget describes where the result set of a query is cached.
+type cacheTarget struct {
+	cache   Cache
+	key     string
+	ttl     time.Duration
+	tagKeys []string // The keys of the tag sets the result set is added to.
+
+	generations *tagGenerations
+	generation  uint64 // Invalidations of the tag keys when the query started.
+
+	encoding *resultEncoding
+
+	now func() time.Time
+}
+
+// cacheTarget returns where the result set of the query is cached, and how the query uses the cache.
+// The target is nil when caching is disabled or the key cannot be built from the arguments.
+// Queries inside a transaction never use the cache: they must see the transaction's own
+// uncommitted writes, and their results must not be published before the commit.
+func (x *dbx) cacheTarget(ctx context.Context, query string, args ...interface{}) (*cacheTarget, cacheMode) {
+	if _, ok := ctx.Value(&contextKeyEnableSqlTx{}).(*sql.Tx); ok {
+		return nil, cacheModeBypass
+	}
+
+	mode := cacheModeFromContext(ctx)
+
+	ttl := cacheTTLFromContext(ctx, x.cacheTTL)
+	if ttl <= 0 {
+		mode = cacheModeBypass
+	}
+
+	if x.cache == nil || mode == cacheModeBypass {
+		return nil, cacheModeBypass
+	}
+
+	queryKey, err := x.keyBuilder.Key(query, args...)
+	if err != nil {
+		log.Printf("cache key error: %v", err)
+		return nil, cacheModeBypass
+	}
+
+	tagKeys := x.tagKeys(x.cacheTags(ctx, query))
+
+	return &cacheTarget{
+		cache:       x.cache,
+		key:         queryKey,
+		ttl:         ttl,
+		tagKeys:     tagKeys,
+		generations: &x.generations,
+		generation:  x.generations.sum(tagKeys),
+		encoding:    &x.encoding,
+		now:         x.now,
+	}, mode
+}
+
+// invalidated reports whether a tag of the target was invalidated since the query started,
+// in which case its result set may predate the write and must not be cached.
+func (t *cacheTarget) invalidated() bool {
+	return t.generations != nil && t.generations.sum(t.tagKeys) != t.generation
+}
+
+// tagGenerationStripes is the number of invalidation counters of a dbx.
+const tagGenerationStripes = 256
+
+// tagGenerations counts the invalidations of tag keys. Tag keys share the counters by hash,
+// so their number stays bounded; a collision only keeps a result set out of the cache.
+type tagGenerations [tagGenerationStripes]uint64
+
+// bump counts an invalidation of the tag key.
+func (g *tagGenerations) bump(tagKey string) {
+	atomic.AddUint64(&g[tagStripe(tagKey)], 1)
+}
+
+// sum returns the number of invalidations of the tag keys, which only grows.
+func (g *tagGenerations) sum(tagKeys []string) uint64 {
+	var n uint64
+	for _, tagKey := range tagKeys {
+		n += atomic.LoadUint64(&g[tagStripe(tagKey)])
+	}
+
+	return n
+}
+
+// tagStripe returns the counter of the tag key.
+func tagStripe(tagKey string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(tagKey))
+
+	return int(h.Sum32() % tagGenerationStripes)
+}
+
+// cachedResult returns the result set cached for the target, and whether it is fresh.
+// A result set that is not fresh is still valid, but due for an early refresh.
+// Cache errors other than a miss are logged and treated as a miss.
+func (x *dbx) cachedResult(ctx context.Context, target *cacheTarget) (*cachedResult, bool, bool) {
+	b, err := target.cache.Get(ctx, target.key)
+	if err != nil {
+		if !errors.Is(err, ErrCacheMiss) {
+			log.Printf("cache get error: %v", err)
+		}
+
+		return nil, false, false
+	}
+
+	res, entry, err := x.encoding.decode(b)
+	if err != nil {
+		log.Printf("cache decode error: %v", err)
+		return nil, false, false
+	}
+
+	return res, !x.refreshEarly(entry), true
+}
+
+// cacheTags returns the tags of a query: the tables it uses and the tags set on the context.
+func (x *dbx) cacheTags(ctx context.Context, query string) []string {
+	return appendTags(internal.Tables(query), cacheTagsFromContext(ctx)...)
+}
+
+// tagKeys returns the cache keys of the tag sets.
+func (x *dbx) tagKeys(tags []string) []string {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = x.keyBuilder.TagKey(tag)
+	}
+
+	return keys
+}
+
+// invalidate invalidates the cached results tagged with the tables written by the query
+// and the tags set on the context. Inside a transaction started by EnableTx the
+// invalidation is deferred until the transaction is committed, and dropped on rollback.
+// Errors are logged, a failed invalidation does not fail the query.
+func (x *dbx) invalidate(ctx context.Context, query string) {
+	if x.cache == nil {
+		return
+	}
+
+	tags := x.cacheTags(ctx, query)
+	if len(tags) == 0 {
+		return
+	}
+
+	invalidateFn := func(ctx context.Context) {
+		if err := x.InvalidateCacheTags(ctx, tags...); err != nil {
+			log.Printf("cache invalidate error: %v", err)
+		}
+	}
+
+	if hooks, ok := ctx.Value(contextKeyTxHooks{}).(*txHooks); ok {
+		hooks.afterCommit(invalidateFn)
+		return
+	}
+
+	invalidateFn(ctx)
+}
+
+// InvalidateCacheTags deletes every cached result tagged with any of the tags.
+// Tags are table names, or the tags set with WithCacheTags. Result sets of this dbx that are
+// still being read when their tags are invalidated are not cached.
+func (x *dbx) InvalidateCacheTags(ctx context.Context, tags ...string) error {
+	if x.cache == nil {
+		return nil
+	}
+
+	var errs []error
+
+	for _, tagKey := range x.tagKeys(appendTags(nil, tags...)) {
+		// Counted before the delete, so a result set stored after the delete sees the change.
+		x.generations.bump(tagKey)
+
+		if err := x.cache.DeleteByTag(ctx, tagKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,10 +1,10 @@
 package rdbx
 
 import (
-	"bytes"
 	"context"
+	"database/sql/driver"
 	"encoding/csv"
-	"encoding/hex"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -30,63 +30,774 @@ func Test_dbx_QueryContext(t *testing.T) {
 		Profession string
 	}
 
+	query := "SELECT * FROM users"
+	queryKey, err := NewHashKeyBuilder("", "").Key(query)
+	assert.NoError(t, err)
+
+	csvReader := csv.NewReader(strings.NewReader(exampleRows))
+	csvReader.Comma = ','
+	csvReader.Comment = '#'
+	records, err := csvReader.ReadAll()
+	assert.NoError(t, err)
+
+	expected := &cachedResult{columns: exampleColumn}
+	for _, record := range records {
+		row := make([]driver.Value, len(record))
+		for j := range record {
+			row[j] = []byte(record[j])
+		}
+
+		expected.rows = append(expected.rows, row)
+	}
+
+	rowsPayload, err := encodeRows(expected)
+	assert.NoError(t, err)
+
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	payload := encodeEntry(cacheEntry{payload: rowsPayload, expiresAt: now.Add(1800 * time.Second)})
+
+	scanAll := func(t *testing.T, rows *Rows) []model {
+		var ms []model
+		for rows.Next() {
+			var m model
+
+			err := rows.Scan(&m.ID, &m.FirstName, &m.LastName, &m.Email, &m.Email2, &m.Profession)
+			assert.NoError(t, err)
+
+			ms = append(ms, m)
+		}
+
+		assert.NoError(t, rows.Err())
+
+		return ms
+	}
+
+	var fromDB []model
+
 	t.Run("if cache not found", func(t *testing.T) {
-		dbx := NewDbx(db, &cache{redis: redisClient})
+		dbx := NewDbx(db, NewRedisCache(redisClient))
+		dbx.now = func() time.Time { return now }
 		rMock.ClearExpect()
-		query := "SELECT * FROM users"
-		queryKey := hex.EncodeToString([]byte(query))
 		rMock.ExpectGet(queryKey).SetErr(redis.Nil)
 
 		exRows := sqlmock.NewRows(exampleColumn).FromCSVString(exampleRows)
 
 		mock.ExpectQuery(query).WillReturnRows(exRows)
 
-		cachedRows := bytes.NewBuffer(nil)
+		rows, err := dbx.QueryContext(context.Background(), query)
+		if err != nil {
+			assert.NoError(t, err)
+			return
+		}
+
+		assert.False(t, rows.Cached())
+
+		fromDB = scanAll(t, rows)
+		assert.Len(t, fromDB, len(records))
+
+		rMock.ExpectEvalSha(tagScript.Hash(), []string{"rdbx:tag:users"}, queryKey, (1800*time.Second + time.Minute).Milliseconds()).SetVal(int64(1))
+		rMock.ExpectSet(queryKey, payload, 1800*time.Second).SetVal("OK")
+
+		assert.NoError(t, rows.Close())
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("if cache found", func(t *testing.T) {
+		dbx := NewDbx(db, NewRedisCache(redisClient))
+		rMock.ClearExpect()
+		rMock.ExpectGet(queryKey).SetVal(string(payload))
 
 		rows, err := dbx.QueryContext(context.Background(), query)
 		if err != nil {
 			assert.NoError(t, err)
 			return
 		}
-		defer func() {
-			err := rows.Close()
+
+		assert.True(t, rows.Cached())
+		assert.Equal(t, fromDB, scanAll(t, rows))
+		assert.NoError(t, rows.Close())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("partially read rows are not cached", func(t *testing.T) {
+		dbx := NewDbx(db, NewRedisCache(redisClient))
+		rMock.ClearExpect()
+		rMock.ExpectGet(queryKey).SetErr(redis.Nil)
+
+		exRows := sqlmock.NewRows(exampleColumn).FromCSVString(exampleRows)
+
+		mock.ExpectQuery(query).WillReturnRows(exRows)
+
+		rows, err := dbx.QueryContext(context.Background(), query)
+		if err != nil {
+			assert.NoError(t, err)
+			return
+		}
+
+		assert.True(t, rows.Next())
+		assert.NoError(t, rows.Close())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, rMock.ExpectationsWereMet())
+	})
+
+	t.Run("no cache", func(t *testing.T) {
+		dbx := NewDbx(db, nil)
+
+		exRows := sqlmock.NewRows(exampleColumn).FromCSVString(exampleRows)
+
+		mock.ExpectQuery(query).WillReturnRows(exRows)
+
+		rows, err := dbx.QueryContext(context.Background(), query)
+		if err != nil {
 			assert.NoError(t, err)
-		}()
+			return
+		}
+
+		assert.Equal(t, fromDB, scanAll(t, rows))
+		assert.NoError(t, rows.Close())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_dbx_Queryx(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type model struct {
+		ID        int64      `column:"id"`
+		Name      string     `column:"name"`
+		Score     float64    `column:"score"`
+		Avatar    []byte     `column:"avatar"`
+		CreatedAt time.Time  `column:"created_at"`
+		Note      NullString `column:"note"`
+	}
+
+	createdAt := time.Date(2023, 5, 1, 10, 30, 0, 123, time.UTC)
+
+	query := "SELECT id, name, score, avatar, created_at, note FROM users"
+
+	c := newMapCache()
+	dbx := NewDbx(db, c, WithCacheNamespace("users", "v1"))
 
-		csvReader := csv.NewReader(strings.NewReader(exampleRows))
-		csvReader.Comma = ','
-		csvReader.Comment = '#'
-		records, err := csvReader.ReadAll()
+	mock.ExpectQuery(query).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "score", "avatar", "created_at", "note"}).
+			AddRow(int64(1), "alie", 1.5, []byte{0, 1, 2}, createdAt, nil).
+			AddRow(int64(2), "binny", 2.25, []byte{}, createdAt, "note"),
+	)
+
+	var fromDB []model
+	err = dbx.Queryx(context.Background(), query, &fromDB)
+	assert.NoError(t, err)
+	assert.Len(t, fromDB, 2)
+	assert.Len(t, c.values, 1)
+
+	for k := range c.values {
+		assert.True(t, strings.HasPrefix(k, "users:v1:"))
+	}
+
+	var fromCache []model
+	err = dbx.Queryx(context.Background(), query, &fromCache)
+	assert.NoError(t, err)
+	assert.Equal(t, fromDB, fromCache)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_dbx_Queryx_nested(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type Audit struct {
+		CreatedAt time.Time `column:"created_at"`
+		UpdatedAt time.Time `column:"updated_at"`
+	}
+
+	type Address struct {
+		City    string `column:"city"`
+		Country string `column:"country"`
+	}
+
+	type model struct {
+		ID int64 `column:"id"`
+		Audit
+		Address  Address  `column:"addr_,prefix"`
+		Billing  *Address `column:"billing_,prefix"`
+		Internal string
+	}
+
+	createdAt := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	updatedAt := createdAt.Add(time.Hour)
+
+	dbx := NewDbx(db, nil)
+
+	mock.ExpectQuery(
+		"SELECT id, created_at, updated_at, addr_city, addr_country, billing_city, billing_country FROM users",
+	).WillReturnRows(
+		sqlmock.NewRows([]string{
+			"id", "created_at", "updated_at", "addr_city", "addr_country", "billing_city", "billing_country",
+		}).
+			AddRow(int64(1), createdAt, updatedAt, "Jakarta", "ID", "Bandung", "ID").
+			AddRow(int64(2), createdAt, updatedAt, "Paris", "FR", "Lyon", "FR"),
+	)
+
+	var got []model
+	err = dbx.Queryx(context.Background(), "SELECT * FROM users", &got)
+	assert.NoError(t, err)
+	assert.Equal(t, []model{
+		{
+			ID:      1,
+			Audit:   Audit{CreatedAt: createdAt, UpdatedAt: updatedAt},
+			Address: Address{City: "Jakarta", Country: "ID"},
+			Billing: &Address{City: "Bandung", Country: "ID"},
+		},
+		{
+			ID:      2,
+			Audit:   Audit{CreatedAt: createdAt, UpdatedAt: updatedAt},
+			Address: Address{City: "Paris", Country: "FR"},
+			Billing: &Address{City: "Lyon", Country: "FR"},
+		},
+	}, got)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_dbx_Queryx_null(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	query := "SELECT id, nickname, deleted_at, score FROM users"
+	columns := []string{"id", "nickname", "deleted_at", "score"}
+	deletedAt := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	expectQuery := func() {
+		mock.ExpectQuery(query).WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow(int64(1), "alie", deletedAt, int64(7)).
+				AddRow(int64(2), nil, nil, nil),
+		)
+	}
+
+	t.Run("pointer fields", func(t *testing.T) {
+		type model struct {
+			ID        int64      `column:"id"`
+			Nickname  *string    `column:"nickname"`
+			DeletedAt *time.Time `column:"deleted_at"`
+			Score     *int32     `column:"score"`
+		}
+
+		expectQuery()
+
+		var got []model
+		assert.NoError(t, NewDbx(db, nil).Queryx(context.Background(), query, &got))
+
+		nickname, score := "alie", int32(7)
+		assert.Equal(t, []model{
+			{ID: 1, Nickname: &nickname, DeletedAt: &deletedAt, Score: &score},
+			{ID: 2},
+		}, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NULL into a value field", func(t *testing.T) {
+		type model struct {
+			ID        int64     `column:"id"`
+			Nickname  string    `column:"nickname"`
+			DeletedAt time.Time `column:"deleted_at"`
+			Score     int32     `column:"score"`
+		}
+
+		expectQuery()
+
+		var got []model
+		err := NewDbx(db, nil).Queryx(context.Background(), query, &got)
+		assert.ErrorIs(t, err, errNullValue)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), `column "nickname" into field model.Nickname (string)`)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("zero on NULL", func(t *testing.T) {
+		type model struct {
+			ID        int64     `column:"id"`
+			Nickname  string    `column:"nickname,zeronull"`
+			DeletedAt time.Time `column:"deleted_at"`
+			Score     int32     `column:"score"`
+		}
+
+		expectQuery()
+
+		var got []model
+		assert.NoError(t, NewDbx(db, nil, WithZeroOnNull()).Queryx(context.Background(), query, &got))
+		assert.Equal(t, []model{
+			{ID: 1, Nickname: "alie", DeletedAt: deletedAt, Score: 7},
+			{ID: 2},
+		}, got)
+
+		expectQuery()
+
+		err := NewDbx(db, nil).Queryx(context.Background(), query, &got)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), `column "deleted_at"`)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unconvertible value", func(t *testing.T) {
+		type model struct {
+			Nickname int64 `column:"nickname"`
+		}
+
+		mock.ExpectQuery("SELECT nickname FROM users").WillReturnRows(sqlmock.NewRows([]string{"nickname"}).AddRow("alie"))
+
+		var got model
+		err := NewDbx(db, nil).Queryx(context.Background(), "SELECT * FROM users", &got)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), `column "nickname" into field model.Nickname (int64)`)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_dbx_Queryx_mapsAndScalars(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	dbx := NewDbx(db, newMapCache())
+
+	mock.ExpectQuery("SELECT * FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, []byte("alie")).AddRow(2, nil))
+
+	// The second query is served from the cache.
+	for i := 0; i < 2; i++ {
+		var rows []map[string]interface{}
+		assert.NoError(t, dbx.Queryx(ctx, "SELECT * FROM users", &rows))
+		assert.Equal(t, []map[string]interface{}{
+			{"id": int64(1), "name": "alie"},
+			{"id": int64(2), "name": nil},
+		}, rows)
+	}
+
+	mock.ExpectQuery("SELECT * FROM users WHERE id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alie"))
+
+	var row map[string]interface{}
+	assert.NoError(t, dbx.Queryx(ctx, "SELECT * FROM users WHERE id = ?", &row, 1))
+	assert.Equal(t, map[string]interface{}{"id": int64(1), "name": "alie"}, row)
+
+	mock.ExpectQuery("SELECT name FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alie").AddRow("bob"))
+
+	var names []string
+	assert.NoError(t, dbx.Queryx(ctx, "SELECT name FROM users", &names))
+	assert.Equal(t, []string{"alie", "bob"}, names)
+
+	mock.ExpectQuery("SELECT nickname FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"nickname"}).AddRow("al").AddRow(nil))
+
+	var nicknames []*string
+	assert.NoError(t, dbx.Queryx(ctx, "SELECT nickname FROM users", &nicknames))
+	if assert.Len(t, nicknames, 2) {
+		assert.Equal(t, "al", *nicknames[0])
+		assert.Nil(t, nicknames[1])
+	}
+
+	mock.ExpectQuery("SELECT COUNT(*) FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	var count int64
+	assert.NoError(t, dbx.Queryx(ctx, "SELECT COUNT(*) FROM users", &count))
+	assert.Equal(t, int64(2), count)
+
+	mock.ExpectQuery("SELECT id, name FROM users LIMIT 1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alie"))
+
+	var id int64
+	err = dbx.Queryx(ctx, "SELECT id, name FROM users LIMIT 1", &id)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "single column")
+	}
+
+	mock.ExpectQuery("SELECT id FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(nil))
+
+	var ids []int64
+	assert.ErrorIs(t, dbx.Queryx(ctx, "SELECT id FROM users", &ids), errNullValue)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_dbx_QueryContext_cacheControl(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	query := "SELECT id FROM users WHERE id = ?"
+
+	queryAll := func(t *testing.T, dbx *dbx, ctx context.Context) *Rows {
+		rows, err := dbx.QueryContext(ctx, query, 1)
 		assert.NoError(t, err)
 
-		var ms []model
+		for rows.Next() {
+		}
+
+		assert.NoError(t, rows.Close())
+
+		return rows
+	}
+
+	expectQuery := func() {
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	}
+
+	t.Run("default ttl", func(t *testing.T) {
+		c := newMapCache()
+		dbx := NewDbx(db, c, WithDefaultCacheTTL(time.Minute))
+
+		expectQuery()
+		queryAll(t, dbx, context.Background())
+
+		assert.Len(t, c.values, 1)
+		for k := range c.values {
+			assert.Equal(t, time.Minute, c.ttls[k])
+		}
+
+		assert.True(t, queryAll(t, dbx, context.Background()).Cached())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("WithCacheTTL", func(t *testing.T) {
+		c := newMapCache()
+		dbx := NewDbx(db, c)
+
+		expectQuery()
+		queryAll(t, dbx, WithCacheTTL(context.Background(), 5*time.Second))
+
+		for k := range c.values {
+			assert.Equal(t, 5*time.Second, c.ttls[k])
+		}
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NoCache", func(t *testing.T) {
+		c := newMapCache()
+		dbx := NewDbx(db, c)
+
+		expectQuery()
+		queryAll(t, dbx, context.Background())
+
+		expectQuery()
+		assert.False(t, queryAll(t, dbx, NoCache(context.Background())).Cached())
+
+		c.values = map[string][]byte{}
+
+		expectQuery()
+		queryAll(t, dbx, NoCache(context.Background()))
+		assert.Empty(t, c.values)
+
+		expectQuery()
+		queryAll(t, dbx, WithCacheTTL(context.Background(), 0))
+		assert.Empty(t, c.values)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("RefreshCache", func(t *testing.T) {
+		c := newMapCache()
+		dbx := NewDbx(db, c)
+
+		expectQuery()
+		queryAll(t, dbx, context.Background())
+
+		for k := range c.values {
+			c.values[k] = []byte("stale")
+		}
+
+		expectQuery()
+		assert.False(t, queryAll(t, dbx, RefreshCache(context.Background())).Cached())
+
+		for k := range c.values {
+			assert.NotEqual(t, []byte("stale"), c.values[k])
+		}
+
+		assert.True(t, queryAll(t, dbx, context.Background()).Cached())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_dbx_ExecContext_invalidate(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	redisClient, _ := redismock.NewClientMock()
+
+	usersQuery := "SELECT id FROM users"
+	ordersQuery := "SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id"
+	reportQuery := "SELECT total FROM daily_report"
+
+	c := newMapCache()
+	dbx := NewDbx(db, c)
+	txx := NewTransactioner(dbx, redisClient)
+
+	cached := func(t *testing.T, query string) bool {
+		rows, err := dbx.QueryContext(context.Background(), query)
+		assert.NoError(t, err)
 
-		i := 0
 		for rows.Next() {
-			var m model
-			i++
+		}
 
-			record := records[i-1]
-			recordByte := make([][]byte, len(record))
-			for j := range record {
-				recordByte[j] = []byte(record[j])
-			}
+		assert.NoError(t, rows.Close())
 
-			d := rows.joinBytes(recordByte)
-			_, err := cachedRows.Write(d)
-			assert.NoError(t, err)
+		return rows.Cached()
+	}
 
-			err = rows.Scan(&m.ID, &m.FirstName, &m.LastName, &m.Email, &m.Email2, &m.Profession)
+	warm := func(t *testing.T, queries ...string) {
+		for _, q := range queries {
+			key, err := dbx.keyBuilder.Key(q)
 			assert.NoError(t, err)
+			assert.NoError(t, c.Delete(context.Background(), key))
+		}
 
-			ms = append(ms, m)
+		for _, q := range queries {
+			mock.ExpectQuery(q).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			assert.False(t, cached(t, q))
 		}
 
-		rMock.ExpectSet(queryKey, cachedRows.Bytes(), 1800*time.Second).SetVal("OK")
-		// fmt.Printf("ms: %v\n", ms)
+		for _, q := range queries {
+			assert.True(t, cached(t, q))
+		}
+	}
+
+	ctxReport := WithCacheTags(context.Background(), "reports")
 
+	mock.ExpectQuery(reportQuery).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(1))
+	rows, err := dbx.QueryContext(ctxReport, reportQuery)
+	assert.NoError(t, err)
+	for rows.Next() {
+	}
+	assert.NoError(t, rows.Close())
+
+	warm(t, usersQuery, ordersQuery)
+
+	t.Run("exec invalidates the tables it writes", func(t *testing.T) {
+		mock.ExpectExec("UPDATE orders SET paid = 1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := dbx.ExecContext(context.Background(), "UPDATE orders SET paid = 1")
+		assert.NoError(t, err)
+
+		assert.True(t, cached(t, usersQuery))
+
+		mock.ExpectQuery(ordersQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		assert.False(t, cached(t, ordersQuery))
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("exec invalidates explicit tags", func(t *testing.T) {
+		mock.ExpectExec("CALL refresh_report()").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := dbx.ExecContext(ctxReport, "CALL refresh_report()")
+		assert.NoError(t, err)
+
+		_, err = c.Get(context.Background(), dbx.keyBuilder.TagKey("reports"))
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.Empty(t, c.lists[dbx.keyBuilder.TagKey("reports")])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("exec in a transaction invalidates on commit", func(t *testing.T) {
+		warm(t, usersQuery, ordersQuery)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM users WHERE id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := txx.EnableTx(context.Background()).Exec(func(ctx context.Context) error {
+			_, err := dbx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", 1)
+			assert.NoError(t, err)
+
+			assert.True(t, cached(t, usersQuery))
+
+			return err
+		})
+		assert.NoError(t, err)
+
+		mock.ExpectQuery(usersQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(ordersQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		assert.False(t, cached(t, usersQuery))
+		assert.False(t, cached(t, ordersQuery))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("queries in a transaction bypass the cache", func(t *testing.T) {
+		warm(t, usersQuery)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(usersQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(reportQuery).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(2))
+		mock.ExpectCommit()
+
+		err := txx.EnableTx(context.Background()).Exec(func(ctx context.Context) error {
+			for _, q := range []string{usersQuery, reportQuery} {
+				rows, err := dbx.QueryContext(ctx, q)
+				if err != nil {
+					return err
+				}
+
+				for rows.Next() {
+				}
+
+				assert.False(t, rows.Cached())
+				assert.NoError(t, rows.Close())
+			}
+
+			return nil
+		})
+		assert.NoError(t, err)
+
+		key, err := dbx.keyBuilder.Key(reportQuery)
+		assert.NoError(t, err)
+
+		_, err = c.Get(context.Background(), key)
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("exec in a rolled back transaction does not invalidate", func(t *testing.T) {
+		warm(t, usersQuery)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM users WHERE id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		err := txx.EnableTx(context.Background()).Exec(func(ctx context.Context) error {
+			_, err := dbx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", 1)
+			assert.NoError(t, err)
+
+			return errors.New("rollback")
+		})
+		assert.Error(t, err)
+
+		assert.True(t, cached(t, usersQuery))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// mapCache is a minimal Cache used to inspect what dbx stores.
+type mapCache struct {
+	values map[string][]byte
+	ttls   map[string]time.Duration
+	lists  map[string][]string
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{values: map[string][]byte{}, ttls: map[string]time.Duration{}, lists: map[string][]string{}}
+}
+
+func (c *mapCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	c.values[key] = append([]byte(nil), value.([]byte)...)
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *mapCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, ok := c.values[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	return v, nil
+}
+
+func (c *mapCache) Append(ctx context.Context, key string, value interface{}) (int64, error) {
+	c.lists[key] = append(c.lists[key], value.(string))
+	return int64(len(c.lists[key])), nil
+}
+
+func (c *mapCache) GetList(ctx context.Context, key string) ([]string, error) {
+	return c.lists[key], nil
+}
+
+func (c *mapCache) Tag(ctx context.Context, tagKey, key string, ttl time.Duration) error {
+	for _, k := range c.lists[tagKey] {
+		if k == key {
+			return nil
+		}
+	}
+
+	c.lists[tagKey] = append(c.lists[tagKey], key)
+	return nil
+}
+
+func (c *mapCache) Delete(ctx context.Context, keys ...string) error {
+	for _, k := range keys {
+		delete(c.values, k)
+		delete(c.ttls, k)
+		delete(c.lists, k)
+	}
+
+	return nil
+}
+
+func (c *mapCache) DeleteByTag(ctx context.Context, tagKey string) error {
+	return c.Delete(ctx, append(c.lists[tagKey], tagKey)...)
+}
+
+func (c *mapCache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	for k := range c.values {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.values, k)
+			delete(c.ttls, k)
+		}
+	}
+
+	for k := range c.lists {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.lists, k)
+		}
+	}
+
+	return nil
+}
+
+func (c *mapCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if _, ok := c.values[key]; !ok {
+		return 0, ErrCacheMiss
+	}
+
+	return c.ttls[key], nil
+}
+
+func (c *mapCache) Stats(ctx context.Context) (CacheStats, error) {
+	return CacheStats{}, nil
+}
+
+// NullString is a sql.NullString used to check that sql.Scanner fields survive the cache.
+type NullString struct {
+	String string
+	Valid  bool
+}
+
+func (n *NullString) Scan(value interface{}) error {
+	if value == nil {
+		n.String, n.Valid = "", false
+		return nil
+	}
+
+	n.Valid = true
+	switch v := value.(type) {
+	case string:
+		n.String = v
+	case []byte:
+		n.String = string(v)
+	}
+
+	return nil
 }
 
 var exampleColumn = []string{"id", "firstname", "lastname", "email", "email2", "profession"}
@@ -1,12 +1,18 @@
 package dbmock
 
 import (
+	"database/sql/driver"
+
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/farislr/commoneer/rdbx"
 	"github.com/farislr/commoneer/rdbx/internal"
 )
 
 type DBTXMock interface {
 	ExpectQueryx(expectedSQL string, model interface{}) *sqlmock.ExpectedQuery
+	ExpectQueryxWithArgs(expectedSQL string, model interface{}, args ...interface{}) *sqlmock.ExpectedQuery
+	ExpectExecWithArgs(expectedSQL string, args ...interface{}) *sqlmock.ExpectedExec
+	ExpectSelect(b *rdbx.SelectBuilder, model interface{}) *sqlmock.ExpectedQuery
 	GetColumns(model interface{}) []string
 
 	sqlmock.Sqlmock
@@ -14,17 +20,86 @@ type DBTXMock interface {
 
 type mock struct {
 	sqlmock.Sqlmock
+
+	dialect rdbx.Dialect
 }
 
-func New(sqlmock sqlmock.Sqlmock) DBTXMock {
-	return &mock{
-		sqlmock,
+func New(sqlmock sqlmock.Sqlmock, options ...Option) DBTXMock {
+	m := &mock{
+		Sqlmock: sqlmock,
+	}
+
+	for _, o := range options {
+		o.Apply(m)
 	}
+
+	return m
+}
+
+// Option represents an option for the mock.
+type Option interface {
+	Apply(*mock)
+}
+
+// optionFunc represents a function that applies an option to the mock.
+type optionFunc func(*mock)
+
+// Apply applies the option to the mock.
+func (f optionFunc) Apply(m *mock) {
+	f(m)
+}
+
+// WithDialect returns an option that rebinds the expected queries to the dialect,
+// as rdbx.WithDialect does for the queries of the dbx under test.
+func WithDialect(d rdbx.Dialect) Option {
+	return optionFunc(func(m *mock) {
+		m.dialect = d
+	})
 }
 
 func (m *mock) ExpectQueryx(expectedSQL string, model interface{}) *sqlmock.ExpectedQuery {
 	e := internal.ModifyOrKeepField(expectedSQL, model)
-	return m.ExpectQuery(e)
+	return m.ExpectQuery(m.dialect.Rebind(e))
+}
+
+// ExpectQueryxWithArgs is ExpectQueryx with the arguments of the query. Slice arguments are
+// expanded the way rdbx expands them, so "id IN (?)" with []int64{1, 2} expects
+// "id IN (?, ?)" with the arguments 1 and 2.
+func (m *mock) ExpectQueryxWithArgs(expectedSQL string, model interface{}, args ...interface{}) *sqlmock.ExpectedQuery {
+	e := internal.ModifyOrKeepField(expectedSQL, model)
+	e, values := m.expand(e, args)
+
+	return m.ExpectQuery(e).WithArgs(values...)
+}
+
+// ExpectExecWithArgs expects an exec of the query with the arguments, slice arguments
+// expanded as with ExpectQueryxWithArgs.
+func (m *mock) ExpectExecWithArgs(expectedSQL string, args ...interface{}) *sqlmock.ExpectedExec {
+	e, values := m.expand(expectedSQL, args)
+
+	return m.ExpectExec(e).WithArgs(values...)
+}
+
+// ExpectSelect expects the query built by b, with its arguments, run through Queryx into the model.
+func (m *mock) ExpectSelect(b *rdbx.SelectBuilder, model interface{}) *sqlmock.ExpectedQuery {
+	query, args := b.ToSQL()
+
+	return m.ExpectQueryxWithArgs(query, model, args...)
+}
+
+// expand expands the slice arguments of the query and rebinds it to the dialect. A query that
+// cannot be expanded is expected as is, and the test then fails on the mismatch.
+func (m *mock) expand(query string, args []interface{}) (string, []driver.Value) {
+	if q, a, err := internal.ExpandIn(query, args, internal.DialectSyntax(m.dialect.String())); err == nil {
+		query, args = q, a
+	}
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+
+	return m.dialect.Rebind(query), values
 }
 
 func (m mock) GetColumns(model interface{}) []string {
@@ -1,11 +1,13 @@
 package dbmock
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/farislr/commoneer/rdbx"
 )
 
 type modelDB struct {
@@ -69,3 +71,59 @@ func Test_mock_ExpectQueryx(t *testing.T) {
 		})
 	}
 }
+
+func Test_mock_ExpectQueryxWithArgs(t *testing.T) {
+	db, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	dbx := rdbx.NewDbx(db, nil, rdbx.WithDialect(rdbx.PostgresDialect))
+	mock := New(sqlMock, WithDialect(rdbx.PostgresDialect))
+
+	mock.ExpectQueryxWithArgs("SELECT * FROM model WHERE name IN (?)", modelDB{}, []string{"a", "b"}).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "created_at"}))
+	mock.ExpectExecWithArgs("DELETE FROM model WHERE name IN (?)", []string{"a"}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var models []modelDB
+	if err := dbx.Queryx(ctx, "SELECT * FROM model WHERE name IN (?)", &models, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dbx.ExecContext(ctx, "DELETE FROM model WHERE name IN (?)", []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_mock_ExpectSelect(t *testing.T) {
+	db, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	dbx := rdbx.NewDbx(db, nil)
+	mock := New(sqlMock)
+
+	b := rdbx.Select().From("model").Where(rdbx.In("name", []string{"a", "b"})).Limit(10)
+
+	mock.ExpectSelect(b, modelDB{}).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "created_at"}))
+
+	query, args := b.ToSQL()
+
+	var models []modelDB
+	if err := dbx.Queryx(ctx, query, &models, args...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,189 @@
+package rdbx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// Dialect is the SQL dialect of the database behind a dbx. Queries are always written with "?"
+// placeholders, and the dialect rebinds them to the placeholders its driver expects.
+type Dialect int
+
+const (
+	MySQLDialect     Dialect = iota // Placeholders are "?".
+	PostgresDialect                 // Placeholders are "$1", "$2", ...
+	SQLiteDialect                   // Placeholders are "?".
+	SQLServerDialect                // Placeholders are "@p1", "@p2", ...
+)
+
+// String returns the name of the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case MySQLDialect:
+		return "mysql"
+	case PostgresDialect:
+		return "postgres"
+	case SQLiteDialect:
+		return "sqlite"
+	case SQLServerDialect:
+		return "sqlserver"
+	default:
+		return "unknown"
+	}
+}
+
+// Rebind rewrites the "?" placeholders of the query to the placeholders of the dialect.
+// With Postgres, the jsonb operators "?", "?|" and "?&" are rewritten too; use the
+// jsonb_exists functions instead.
+func (d Dialect) Rebind(query string) string {
+	switch d {
+	case PostgresDialect:
+		return internal.Rebind(query, "$", d.syntax())
+	case SQLServerDialect:
+		return internal.Rebind(query, "@p", d.syntax())
+	default:
+		return query
+	}
+}
+
+// syntax returns how the string literals of the dialect are quoted.
+func (d Dialect) syntax() internal.Syntax {
+	return internal.DialectSyntax(d.String())
+}
+
+// WithDialect returns an option that sets the SQL dialect of the database, MySQLDialect by default.
+// Every query run through the dbx, including named and generic queries, is rebound to it.
+func WithDialect(d Dialect) DbxOption {
+	return dbxOptionFunc(func(x *dbx) {
+		x.dialect = d
+	})
+}
+
+// dialecter is implemented by the DBTX that know the dialect of their database, such as dbx.
+type dialecter interface {
+	sqlDialect() Dialect
+}
+
+// sqlDialect returns the dialect set with WithDialect.
+func (x *dbx) sqlDialect() Dialect {
+	return x.dialect
+}
+
+// dialectOf returns the dialect of db, MySQLDialect when db does not know it.
+func dialectOf(db DBTX) Dialect {
+	if d, ok := db.(dialecter); ok {
+		return d.sqlDialect()
+	}
+
+	return MySQLDialect
+}
+
+// maxArgs returns the most arguments a single statement can bind.
+func (d Dialect) maxArgs() int {
+	switch d {
+	case SQLiteDialect:
+		return 32766 // SQLITE_MAX_VARIABLE_NUMBER since SQLite 3.32.
+	case SQLServerDialect:
+		return 2100
+	default:
+		return 65535
+	}
+}
+
+// hasLastInsertID reports whether the drivers of the dialect return the id of an inserted row
+// from sql.Result.LastInsertId.
+func (d Dialect) hasLastInsertID() bool {
+	return d == MySQLDialect || d == SQLiteDialect
+}
+
+// upsertClause returns the clause that turns an INSERT into an upsert: when a row conflicts on the
+// key columns, its update columns are set to the inserted values.
+func (d Dialect) upsertClause(keys, update []string) (string, error) {
+	var b strings.Builder
+
+	switch d {
+	case MySQLDialect:
+		// MySQL conflicts on any unique key, the key columns only matter when nothing is updated.
+		if len(update) == 0 {
+			if len(keys) == 0 {
+				return "", errors.New("rdbx: upsert needs a pk column or a column to update")
+			}
+
+			update = keys[:1]
+		}
+
+		b.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, c := range update {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+
+			fmt.Fprintf(&b, "%s = VALUES(%s)", c, c)
+		}
+	case PostgresDialect, SQLiteDialect:
+		if len(keys) == 0 {
+			return "", fmt.Errorf("rdbx: upsert with %s needs a pk column to conflict on", d)
+		}
+
+		fmt.Fprintf(&b, " ON CONFLICT (%s) DO ", strings.Join(keys, ", "))
+		if len(update) == 0 {
+			b.WriteString("NOTHING")
+			break
+		}
+
+		b.WriteString("UPDATE SET ")
+		for i, c := range update {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+
+			fmt.Fprintf(&b, "%s = EXCLUDED.%s", c, c)
+		}
+	default:
+		return "", fmt.Errorf("rdbx: upsert is not supported with %s", d)
+	}
+
+	return b.String(), nil
+}
+
+// limitClause returns the clause that skips offset rows and returns at most limit rows, a limit
+// of zero meaning no limit. SQL Server pages with OFFSET FETCH, which needs the query to be ordered.
+func (d Dialect) limitClause(limit, offset int, ordered bool) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+
+	if d == SQLServerDialect {
+		clause := fmt.Sprintf(" OFFSET %d ROWS", offset)
+		if !ordered {
+			clause = " ORDER BY (SELECT NULL)" + clause
+		}
+
+		if limit > 0 {
+			clause += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", limit)
+		}
+
+		return clause
+	}
+
+	var clause string
+
+	switch {
+	case limit > 0:
+		clause = fmt.Sprintf(" LIMIT %d", limit)
+	case d == MySQLDialect:
+		// MySQL and SQLite only accept an OFFSET after a LIMIT.
+		clause = " LIMIT 18446744073709551615"
+	case d == SQLiteDialect:
+		clause = " LIMIT -1"
+	}
+
+	if offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", offset)
+	}
+
+	return clause
+}
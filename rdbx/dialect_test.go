@@ -0,0 +1,58 @@
+package rdbx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_Rebind(t *testing.T) {
+	query := "SELECT * FROM users WHERE id = ? AND name = ?"
+
+	assert.Equal(t, query, MySQLDialect.Rebind(query))
+	assert.Equal(t, query, SQLiteDialect.Rebind(query))
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1 AND name = $2", PostgresDialect.Rebind(query))
+	assert.Equal(t, "SELECT * FROM users WHERE id = @p1 AND name = @p2", SQLServerDialect.Rebind(query))
+}
+
+func Test_dbx_WithDialect(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID   int64  `column:"id"`
+		Name string `column:"name"`
+	}
+
+	ctx := context.Background()
+	dbx := NewDbx(db, newMapCache(), WithDialect(PostgresDialect))
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alie"))
+
+	for i := 0; i < 2; i++ {
+		var u user
+		assert.NoError(t, dbx.Queryx(ctx, "SELECT * FROM users WHERE id = ?", &u, 1))
+		assert.Equal(t, user{ID: 1, Name: "alie"}, u)
+	}
+
+	mock.ExpectExec("UPDATE users SET name = $1 WHERE id = $2").
+		WithArgs("bob", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = dbx.NamedExecContext(ctx, "UPDATE users SET name = :name WHERE id = :id", user{ID: 1, Name: "bob"})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("SELECT name FROM users WHERE id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("bob"))
+
+	var name string
+	assert.NoError(t, dbx.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", 1).Scan(&name))
+	assert.Equal(t, "bob", name)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
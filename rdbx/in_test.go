@@ -0,0 +1,88 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbx_QueryContext_in(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID int64 `column:"id"`
+	}
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil, WithDialect(PostgresDialect))
+
+	mock.ExpectQuery("SELECT id FROM users WHERE id IN ($1, $2) AND active = $3").
+		WithArgs(int64(1), int64(2), true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	var users []user
+	assert.NoError(t, dbx.Queryx(ctx, "SELECT * FROM users WHERE id IN (?) AND active = ?", &users, []int64{1, 2}, true))
+	assert.Equal(t, []user{{ID: 1}, {ID: 2}}, users)
+
+	mock.ExpectQuery("SELECT id FROM users WHERE id IN (NULL)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	users = nil
+	assert.NoError(t, dbx.Queryx(ctx, "SELECT * FROM users WHERE id IN (?)", &users, []int64{}))
+	assert.Empty(t, users)
+
+	mock.ExpectExec("DELETE FROM users WHERE id IN ($1, $2)").
+		WithArgs(int64(3), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	_, err = dbx.NamedExecContext(ctx, "DELETE FROM users WHERE id IN (:ids)", map[string]interface{}{"ids": []int64{3, 4}})
+	assert.NoError(t, err)
+
+	_, err = dbx.ExecContext(ctx, "DELETE FROM users WHERE id IN (?)", []int64{1}, 2)
+	assert.Error(t, err)
+
+	_, err = dbx.ExecContext(ctx, "DELETE FROM users WHERE id NOT IN (?)", []int64{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "NOT IN")
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// arrayConverter passes slices to the driver as is, as drivers with array support do.
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	return v, nil
+}
+
+func Test_dbx_ExecContext_array(t *testing.T) {
+	db, mock, err := sqlmock.New(
+		sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual),
+		sqlmock.ValueConverterOption(arrayConverter{}),
+	)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil, WithDialect(PostgresDialect))
+
+	mock.ExpectExec("DELETE FROM users WHERE id = ANY($1)").
+		WithArgs([]int64{1, 2}).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	_, err = dbx.ExecContext(ctx, "DELETE FROM users WHERE id = ANY($1)", []int64{1, 2})
+	assert.NoError(t, err)
+
+	mock.ExpectExec("DELETE FROM users WHERE id = ANY($1) AND active = $2").
+		WithArgs([]int64{3}, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = dbx.ExecContext(ctx, "DELETE FROM users WHERE id = ANY(?) AND active = ?", []int64{3}, false)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
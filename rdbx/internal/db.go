@@ -5,16 +5,14 @@ import (
 	"strings"
 )
 
+// ModifyOrKeepField expands the "*" and "alias.*" items of the select list of the query
+// to the columns of the model, see ExpandSelectStar.
 func ModifyOrKeepField(existingQuery string, model interface{}) (query string) {
 	if !strings.Contains(existingQuery, "*") {
 		return existingQuery
 	}
 
-	fields := GetColumns(model)
-
-	query = strings.ReplaceAll(existingQuery, "*", strings.Join(fields, ", "))
-
-	return query
+	return ExpandSelectStar(existingQuery, GetColumns(model))
 }
 
 func GetColumns(model interface{}) []string {
@@ -24,13 +22,10 @@ func GetColumns(model interface{}) []string {
 		value = reflect.Indirect(reflect.New(value.Type().Elem()))
 	}
 
-	rType := value.Type()
-	var fields []string
-	for i := 0; i < value.NumField(); i++ {
-		if v, ok := rType.Field(i).Tag.Lookup("column"); ok {
-			fields = append(fields, v)
-		}
+	// Maps and scalars have no known columns.
+	if value.Kind() != reflect.Struct {
+		return nil
 	}
 
-	return fields
+	return MappingOf(value.Type()).Columns()
 }
@@ -39,6 +39,14 @@ func TestModifyOrKeepField(t *testing.T) {
 			},
 			wantQuery: "SELECT name, created_at FROM model",
 		},
+		{
+			name: "count and comments",
+			args: args{
+				existingQuery: "SELECT * /* all */ FROM model WHERE (SELECT COUNT(*) FROM other) > 0",
+				model:         model,
+			},
+			wantQuery: "SELECT name, created_at /* all */ FROM model WHERE (SELECT COUNT(*) FROM other) > 0",
+		},
 		{
 			name: "no asterix",
 			args: args{
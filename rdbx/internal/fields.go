@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ColumnTag is the struct tag that maps a field to a column.
+const ColumnTag = "column"
+
+// scannerType is the type of sql.Scanner.
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// Field is a struct field mapped to a column.
+type Field struct {
+	Column  string
+	Name    string // The path of the field, e.g. "Address.City".
+	Index   []int  // The index path of the field, for FieldByIndex.
+	Options TagOptions
+}
+
+// TagOptions are the comma separated options that follow the column name in a column tag.
+type TagOptions []string
+
+// Has reports whether the option is set.
+func (o TagOptions) Has(option string) bool {
+	for _, opt := range o {
+		if opt == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseColumnTag splits a column tag such as `column:"addr_,prefix"` into the column name and its options.
+func ParseColumnTag(tag string) (string, TagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	if opts == "" {
+		return name, nil
+	}
+
+	return name, strings.Split(opts, ",")
+}
+
+// Mapping maps the columns of a struct type to its fields.
+type Mapping struct {
+	Fields []Field
+
+	byColumn map[string]int
+}
+
+// Field returns the field mapped to the column.
+func (m *Mapping) Field(column string) (*Field, bool) {
+	i, ok := m.byColumn[column]
+	if !ok {
+		return nil, false
+	}
+
+	return &m.Fields[i], true
+}
+
+// Columns returns the mapped columns in declaration order.
+func (m *Mapping) Columns() []string {
+	columns := make([]string, len(m.Fields))
+	for i, f := range m.Fields {
+		columns[i] = f.Column
+	}
+
+	return columns
+}
+
+// mappings caches the *Mapping of every reflect.Type seen by MappingOf.
+var mappings sync.Map
+
+// MappingOf returns the mapping of a struct type. It is computed once per type, as struct tags
+// cannot change at run time, and must not be modified.
+func MappingOf(t reflect.Type) *Mapping {
+	if m, ok := mappings.Load(t); ok {
+		return m.(*Mapping)
+	}
+
+	m := &Mapping{Fields: Fields(t), byColumn: map[string]int{}}
+	for i, f := range m.Fields {
+		m.byColumn[f.Column] = i
+	}
+
+	actual, _ := mappings.LoadOrStore(t, m)
+
+	return actual.(*Mapping)
+}
+
+// Fields returns the fields of a struct type that are mapped to columns, in declaration order.
+// Anonymous struct fields without a column tag are flattened, so an embedded Audit struct
+// contributes its own columns. Struct fields tagged with the prefix option, e.g.
+// `column:"addr_,prefix"`, are flattened too, their columns prefixed with the tag name.
+// When several fields map to the same column the shallowest one wins, like Go field promotion.
+func Fields(t reflect.Type) []Field {
+	var fields []Field
+
+	depths := map[string]int{}
+	collectFields(t, "", "", nil, &fields, depths)
+
+	return fields
+}
+
+// collectFields appends the fields of the struct type to fields, their columns prefixed with prefix
+// and their names with path.
+func collectFields(t reflect.Type, prefix, path string, index []int, fields *[]Field, depths map[string]int) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		fieldPath := path + sf.Name
+
+		tag, tagged := sf.Tag.Lookup(ColumnTag)
+		name, opts := ParseColumnTag(tag)
+
+		switch {
+		case tagged && opts.Has("prefix"):
+			collectFields(sf.Type, prefix+name, fieldPath+".", fieldIndex, fields, depths)
+			continue
+		case !tagged && sf.Anonymous && isFlattenable(sf.Type):
+			collectFields(sf.Type, prefix, fieldPath+".", fieldIndex, fields, depths)
+			continue
+		case !tagged || name == "-" || !sf.IsExported():
+			continue
+		}
+
+		column := prefix + name
+
+		if depth, ok := depths[column]; ok {
+			if depth <= len(fieldIndex) {
+				continue
+			}
+
+			*fields = removeColumn(*fields, column)
+		}
+
+		depths[column] = len(fieldIndex)
+		*fields = append(*fields, Field{Column: column, Name: fieldPath, Index: fieldIndex, Options: opts})
+	}
+}
+
+// isFlattenable reports whether an anonymous field is a struct whose fields are columns,
+// rather than a value scanned as a whole such as time.Time or sql.NullString.
+func isFlattenable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(scannerType)
+}
+
+// removeColumn removes the field mapped to the column.
+func removeColumn(fields []Field, column string) []Field {
+	for i, f := range fields {
+		if f.Column == column {
+			return append(fields[:i], fields[i+1:]...)
+		}
+	}
+
+	return fields
+}
+
+// FieldByIndex returns the field of the struct value at the index path, allocating the nil
+// pointers to embedded or prefixed structs on the way. It returns an invalid Value when a
+// nil pointer cannot be allocated, e.g. an unexported embedded pointer.
+func FieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v
+}
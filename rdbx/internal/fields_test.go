@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fieldsAudit struct {
+	CreatedAt time.Time `column:"created_at"`
+	UpdatedAt time.Time `column:"updated_at"`
+}
+
+type fieldsAddress struct {
+	City string `column:"city"`
+	Zip  string `column:"zip"`
+}
+
+type fieldsModel struct {
+	ID int64 `column:"id,pk"`
+	fieldsAudit
+	*sql.NullString
+	Address   fieldsAddress  `column:"addr_,prefix"`
+	Billing   *fieldsAddress `column:"billing_,prefix"`
+	UpdatedAt time.Time      `column:"updated_at"`
+	Ignored   string         `column:"-"`
+	Plain     string
+	private   string `column:"private"`
+}
+
+func TestFields(t *testing.T) {
+	got := Fields(reflect.TypeOf(fieldsModel{}))
+
+	want := []Field{
+		{Column: "id", Name: "ID", Index: []int{0}, Options: TagOptions{"pk"}},
+		{Column: "created_at", Name: "fieldsAudit.CreatedAt", Index: []int{1, 0}},
+		{Column: "addr_city", Name: "Address.City", Index: []int{3, 0}},
+		{Column: "addr_zip", Name: "Address.Zip", Index: []int{3, 1}},
+		{Column: "billing_city", Name: "Billing.City", Index: []int{4, 0}},
+		{Column: "billing_zip", Name: "Billing.Zip", Index: []int{4, 1}},
+		{Column: "updated_at", Name: "UpdatedAt", Index: []int{5}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %+v, want %+v", got, want)
+	}
+
+}
+
+func TestMappingOf(t *testing.T) {
+	m := MappingOf(reflect.TypeOf(fieldsModel{}))
+	if m != MappingOf(reflect.TypeOf(fieldsModel{})) {
+		t.Errorf("MappingOf() is not cached")
+	}
+
+	f, ok := m.Field("billing_zip")
+	if !ok || !reflect.DeepEqual(f.Index, []int{4, 1}) {
+		t.Errorf("Mapping.Field() = %+v, %v", f, ok)
+	}
+
+	if _, ok := m.Field("private"); ok {
+		t.Errorf("Mapping.Field() maps an unexported field")
+	}
+
+	want := []string{"id", "created_at", "addr_city", "addr_zip", "billing_city", "billing_zip", "updated_at"}
+	if got := m.Columns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mapping.Columns() = %v, want %v", got, want)
+	}
+}
+
+func TestParseColumnTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		wantName string
+		wantOpts TagOptions
+	}{
+		{tag: "id", wantName: "id"},
+		{tag: "id,pk,autoincrement", wantName: "id", wantOpts: TagOptions{"pk", "autoincrement"}},
+		{tag: "addr_,prefix", wantName: "addr_", wantOpts: TagOptions{"prefix"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			name, opts := ParseColumnTag(tt.tag)
+			if name != tt.wantName || !reflect.DeepEqual(opts, tt.wantOpts) {
+				t.Errorf("ParseColumnTag() = %v, %v, want %v, %v", name, opts, tt.wantName, tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestFieldByIndex(t *testing.T) {
+	var m fieldsModel
+
+	f := FieldByIndex(reflect.ValueOf(&m).Elem(), []int{4, 0})
+	f.SetString("Bandung")
+
+	if m.Billing == nil || m.Billing.City != "Bandung" {
+		t.Errorf("FieldByIndex() did not allocate the nil pointer: %+v", m.Billing)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ExpandIn expands the "?" placeholders that are the only item of an IN list and whose argument
+// is a slice to one placeholder per element, so "id IN (?)" with []int64{1, 2, 3} becomes
+// "id IN (?, ?, ?)" with the elements as arguments. An empty slice becomes NULL, which no row
+// matches. After NOT IN, where every row should match but NOT IN (NULL) matches none, an empty
+// slice is an error. Any other slice, as in "id = ANY(?)", a []byte and a driver.Valuer are
+// single values and are left as is. Without slice arguments, or without "?" placeholders, as in
+// "id = ANY($1)", the query and its arguments are returned unchanged.
+func ExpandIn(query string, args []interface{}, syntax Syntax) (string, []interface{}, error) {
+	if !hasList(args) || !strings.Contains(query, "?") {
+		return query, args, nil
+	}
+
+	tokens := TokenizeSyntax(query, syntax)
+
+	expanded := make([]interface{}, 0, len(args))
+
+	n := 0
+	for i, t := range tokens {
+		if t.Kind != TokenPunct || t.Text != "?" {
+			continue
+		}
+
+		if n == len(args) {
+			return "", nil, fmt.Errorf("rdbx: query has more placeholders than its %d arguments", len(args))
+		}
+
+		arg := args[n]
+		n++
+
+		in, notIn := inList(tokens, i)
+		if !in || !isList(arg) {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		v := reflect.ValueOf(arg)
+		if v.Len() == 0 {
+			if notIn {
+				return "", nil, fmt.Errorf("rdbx: empty slice for NOT IN at argument %d, drop the condition instead", n)
+			}
+
+			tokens[i].Text = "NULL"
+			continue
+		}
+
+		tokens[i].Text = strings.Repeat("?, ", v.Len()-1) + "?"
+		for j := 0; j < v.Len(); j++ {
+			expanded = append(expanded, v.Index(j).Interface())
+		}
+	}
+
+	if n == 0 {
+		return query, args, nil
+	}
+
+	if n != len(args) {
+		return "", nil, fmt.Errorf("rdbx: query has %d placeholders for %d arguments", n, len(args))
+	}
+
+	return JoinTokens(tokens), expanded, nil
+}
+
+// inList reports whether the token at i is the only item of an IN list, as in "IN (?)",
+// and whether the list follows NOT IN.
+func inList(tokens []Token, i int) (in, notIn bool) {
+	if next := nextSignificant(tokens, i); next < 0 || !tokens[next].Is(")") {
+		return false, false
+	}
+
+	open := prevSignificant(tokens, i)
+	if open < 0 || !tokens[open].Is("(") {
+		return false, false
+	}
+
+	op := prevSignificant(tokens, open)
+	if op < 0 || !tokens[op].Is("IN") {
+		return false, false
+	}
+
+	not := prevSignificant(tokens, op)
+
+	return true, not >= 0 && tokens[not].Is("NOT")
+}
+
+// hasList reports whether any of the arguments is a slice to expand.
+func hasList(args []interface{}) bool {
+	for _, arg := range args {
+		if isList(arg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isList reports whether the argument is a slice to expand.
+func isList(arg interface{}) bool {
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+
+	t := reflect.TypeOf(arg)
+
+	return t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestExpandIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		args     []interface{}
+		want     string
+		wantArgs []interface{}
+		syntax   Syntax
+		wantErr  bool
+	}{
+		{
+			name:     "slice",
+			query:    "SELECT * FROM users WHERE id IN (?) AND active = ?",
+			args:     []interface{}{[]int64{1, 2, 3}, true},
+			want:     "SELECT * FROM users WHERE id IN (?, ?, ?) AND active = ?",
+			wantArgs: []interface{}{int64(1), int64(2), int64(3), true},
+		},
+		{
+			name:     "empty slice",
+			query:    "SELECT * FROM users WHERE id IN (?)",
+			args:     []interface{}{[]string{}},
+			want:     "SELECT * FROM users WHERE id IN (NULL)",
+			wantArgs: []interface{}{},
+		},
+		{
+			name:     "not in",
+			query:    "SELECT * FROM users WHERE id NOT IN (?)",
+			args:     []interface{}{[]int64{1, 2}},
+			want:     "SELECT * FROM users WHERE id NOT IN (?, ?)",
+			wantArgs: []interface{}{int64(1), int64(2)},
+		},
+		{
+			name:    "empty slice after not in",
+			query:   "SELECT * FROM users WHERE id not /* ids */ IN ( ?)",
+			args:    []interface{}{[]int64{}},
+			wantErr: true,
+		},
+		{
+			name:     "bytes and valuers",
+			query:    "SELECT '?' FROM t WHERE a = ? AND b IN (?) AND c = ?",
+			args:     []interface{}{[]byte("x"), []string{"y"}, sql.NullString{}},
+			want:     "SELECT '?' FROM t WHERE a = ? AND b IN (?) AND c = ?",
+			wantArgs: []interface{}{[]byte("x"), "y", sql.NullString{}},
+		},
+		{
+			name:     "no slice",
+			query:    "SELECT * FROM users WHERE id = ?",
+			args:     []interface{}{1},
+			want:     "SELECT * FROM users WHERE id = ?",
+			wantArgs: []interface{}{1},
+		},
+		{
+			name:     "not the only item of an in list",
+			query:    "SELECT * FROM t WHERE a = ANY(?) AND b IN (?, ?) AND c IN ((?))",
+			args:     []interface{}{[]int64{1, 2}, []int64{3}, []int64{4}, []int64{5}},
+			want:     "SELECT * FROM t WHERE a = ANY(?) AND b IN (?, ?) AND c IN ((?))",
+			wantArgs: []interface{}{[]int64{1, 2}, []int64{3}, []int64{4}, []int64{5}},
+		},
+		{
+			name:     "in list of a postgres dollar quoted string",
+			query:    "SELECT $$ IN (?) $$, a FROM t WHERE b IN (?)",
+			args:     []interface{}{[]int64{1, 2}},
+			syntax:   PostgresSyntax,
+			want:     "SELECT $$ IN (?) $$, a FROM t WHERE b IN (?, ?)",
+			wantArgs: []interface{}{int64(1), int64(2)},
+		},
+		{
+			name:     "numbered placeholders",
+			query:    "SELECT * FROM users WHERE id = ANY($1)",
+			args:     []interface{}{[]int64{1, 2}},
+			want:     "SELECT * FROM users WHERE id = ANY($1)",
+			wantArgs: []interface{}{[]int64{1, 2}},
+		},
+		{
+			name:    "too many arguments",
+			query:   "SELECT * FROM users WHERE id IN (?)",
+			args:    []interface{}{[]int{1}, 2},
+			wantErr: true,
+		},
+		{
+			name:    "too few arguments",
+			query:   "SELECT * FROM users WHERE id IN (?) AND a = ?",
+			args:    []interface{}{[]int{1}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := ExpandIn(tt.query, tt.args, tt.syntax)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandIn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ExpandIn() query = %q, want %q", got, tt.want)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("ExpandIn() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package internal
+
+// CompileNamed replaces the ":name" parameters of the query with "?" placeholders and returns
+// the names in the order they appear. Names in string literals, quoted identifiers and comments
+// are left as is, and so are casts such as "::text" and assignments such as ":=".
+func CompileNamed(query string) (string, []string) {
+	tokens := Tokenize(query)
+
+	var names []string
+
+	for i := 0; i+1 < len(tokens); i++ {
+		if !tokens[i].Is(":") || tokens[i+1].Kind != TokenWord {
+			continue
+		}
+
+		if i > 0 && tokens[i-1].Is(":") {
+			continue
+		}
+
+		names = append(names, tokens[i+1].Text)
+		tokens[i].Text = "?"
+		tokens[i+1].Text = ""
+		i++
+	}
+
+	if len(names) == 0 {
+		return query, nil
+	}
+
+	return JoinTokens(tokens), names
+}
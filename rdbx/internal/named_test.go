@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompileNamed(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      string
+		wantNames []string
+	}{
+		{
+			name:      "names",
+			query:     "UPDATE users SET name=:name WHERE id = :id",
+			want:      "UPDATE users SET name=? WHERE id = ?",
+			wantNames: []string{"name", "id"},
+		},
+		{
+			name:      "repeated name",
+			query:     "SELECT * FROM users WHERE name = :q OR email = :q",
+			want:      "SELECT * FROM users WHERE name = ? OR email = ?",
+			wantNames: []string{"q", "q"},
+		},
+		{
+			name:      "literals and comments",
+			query:     "SELECT ':skip', `:skip` FROM t -- :skip\nWHERE id = :id /* :skip */",
+			want:      "SELECT ':skip', `:skip` FROM t -- :skip\nWHERE id = ? /* :skip */",
+			wantNames: []string{"id"},
+		},
+		{
+			name:      "cast and assignment",
+			query:     "SELECT @n := :n, created_at::date FROM t",
+			want:      "SELECT @n := ?, created_at::date FROM t",
+			wantNames: []string{"n"},
+		},
+		{
+			name:  "no names",
+			query: "SELECT * FROM users WHERE id = ?",
+			want:  "SELECT * FROM users WHERE id = ?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, names := CompileNamed(tt.query)
+			if got != tt.want {
+				t.Errorf("CompileNamed() query = %q, want %q", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("CompileNamed() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import "strings"
+
+// selectListEnd are the words that end a select list.
+var selectListEnd = map[string]bool{
+	"from": true, "into": true, "where": true, "group": true, "having": true, "order": true,
+	"limit": true, "offset": true, "union": true, "except": true, "intersect": true,
+	"window": true, "for": true,
+}
+
+// ExpandSelectStar replaces the "*" and "alias.*" items of the top-level select lists of the
+// query with the columns, qualified with the alias for "alias.*". A "*" anywhere else, such
+// as in COUNT(*), a multiplication, a subquery, a string literal or a comment, is left as is.
+// Every select list at the top level is expanded, e.g. both sides of a UNION.
+func ExpandSelectStar(query string, columns []string) string {
+	if len(columns) == 0 || !strings.Contains(query, "*") {
+		return query
+	}
+
+	tokens := Tokenize(query)
+
+	depth := 0
+	inList := false
+	expanded := false
+
+	for i, t := range tokens {
+		switch {
+		case t.Is("("):
+			depth++
+			continue
+		case t.Is(")"):
+			depth--
+			continue
+		case depth != 0:
+			continue
+		case t.Is("select"):
+			inList = true
+			continue
+		case !inList:
+			continue
+		case t.Kind == TokenWord && selectListEnd[strings.ToLower(t.Text)] || t.Is(";"):
+			inList = false
+			continue
+		case !t.Is("*"):
+			continue
+		}
+
+		p := prevSignificant(tokens, i)
+		if p < 0 {
+			continue
+		}
+
+		if startsSelectItem(tokens, p) {
+			tokens[i].Text = strings.Join(columns, ", ")
+			expanded = true
+
+			continue
+		}
+
+		if !tokens[p].Is(".") {
+			continue
+		}
+
+		q := prevSignificant(tokens, p)
+		if q < 0 || tokens[q].Kind != TokenWord && tokens[q].Kind != TokenQuoted {
+			continue
+		}
+
+		if r := prevSignificant(tokens, q); r < 0 || !startsSelectItem(tokens, r) {
+			continue
+		}
+
+		qualified := make([]string, len(columns))
+		for j, c := range columns {
+			qualified[j] = tokens[q].Text + "." + c
+		}
+
+		tokens[q].Text = strings.Join(qualified, ", ")
+		for j := q + 1; j <= i; j++ {
+			tokens[j].Text = ""
+		}
+
+		expanded = true
+	}
+
+	if !expanded {
+		return query
+	}
+
+	return JoinTokens(tokens)
+}
+
+// startsSelectItem reports whether the token at i is followed by a new item of a select list.
+func startsSelectItem(tokens []Token, i int) bool {
+	t := tokens[i]
+
+	return t.Is(",") || t.Is("select") || t.Is("distinct") || t.Is("all")
+}
+
+// prevSignificant returns the index of the last token before i that is neither whitespace
+// nor a comment, or -1.
+func prevSignificant(tokens []Token, i int) int {
+	for i--; i >= 0; i-- {
+		if tokens[i].Kind != TokenSpace && tokens[i].Kind != TokenComment {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// nextSignificant returns the index of the first token after i that is neither whitespace
+// nor a comment, or -1.
+func nextSignificant(tokens []Token, i int) int {
+	for i++; i < len(tokens); i++ {
+		if tokens[i].Kind != TokenSpace && tokens[i].Kind != TokenComment {
+			return i
+		}
+	}
+
+	return -1
+}
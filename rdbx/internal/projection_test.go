@@ -0,0 +1,75 @@
+package internal
+
+import "testing"
+
+func TestExpandSelectStar(t *testing.T) {
+	columns := []string{"id", "name"}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "star",
+			query: "SELECT * FROM users",
+			want:  "SELECT id, name FROM users",
+		},
+		{
+			name:  "distinct star",
+			query: "select distinct * from users",
+			want:  "select distinct id, name from users",
+		},
+		{
+			name:  "qualified star",
+			query: "SELECT u.* FROM users u JOIN orders o ON o.user_id = u.id",
+			want:  "SELECT u.id, u.name FROM users u JOIN orders o ON o.user_id = u.id",
+		},
+		{
+			name:  "quoted qualifier with other items",
+			query: "SELECT `u` . *, o.total FROM users `u`, orders o",
+			want:  "SELECT `u`.id, `u`.name, o.total FROM users `u`, orders o",
+		},
+		{
+			name:  "count star",
+			query: "SELECT COUNT(*) FROM users",
+			want:  "SELECT COUNT(*) FROM users",
+		},
+		{
+			name:  "multiplication",
+			query: "SELECT price * quantity AS total FROM items WHERE price * 2 > 10",
+			want:  "SELECT price * quantity AS total FROM items WHERE price * 2 > 10",
+		},
+		{
+			name:  "subquery",
+			query: "SELECT * FROM users WHERE id IN (SELECT * FROM admins)",
+			want:  "SELECT id, name FROM users WHERE id IN (SELECT * FROM admins)",
+		},
+		{
+			name:  "literals and comments",
+			query: "/* SELECT * */ SELECT *, '*' AS star -- all *\nFROM users",
+			want:  "/* SELECT * */ SELECT id, name, '*' AS star -- all *\nFROM users",
+		},
+		{
+			name:  "union",
+			query: "SELECT * FROM users UNION ALL SELECT * FROM archived_users",
+			want:  "SELECT id, name FROM users UNION ALL SELECT id, name FROM archived_users",
+		},
+		{
+			name:  "cte",
+			query: "WITH a AS (SELECT * FROM users) SELECT * FROM a",
+			want:  "WITH a AS (SELECT * FROM users) SELECT id, name FROM a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandSelectStar(tt.query, columns); got != tt.want {
+				t.Errorf("ExpandSelectStar() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := ExpandSelectStar("SELECT * FROM users", nil); got != "SELECT * FROM users" {
+		t.Errorf("ExpandSelectStar() without columns = %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rebind replaces the "?" placeholders of the query with numbered ones, the prefix followed by
+// the position of the placeholder, e.g. "$1" or "@p1". A "?" in a string literal, a quoted
+// identifier or a comment is left as is, string literals being read with the syntax.
+func Rebind(query, prefix string, syntax Syntax) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	tokens := TokenizeSyntax(query, syntax)
+
+	n := 0
+	for i, t := range tokens {
+		if t.Kind == TokenPunct && t.Text == "?" {
+			n++
+			tokens[i].Text = prefix + strconv.Itoa(n)
+		}
+	}
+
+	if n == 0 {
+		return query
+	}
+
+	return JoinTokens(tokens)
+}
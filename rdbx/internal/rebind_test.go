@@ -0,0 +1,77 @@
+package internal
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		prefix string
+		syntax Syntax
+		want   string
+	}{
+		{
+			name:   "dollar",
+			query:  "UPDATE users SET name = ? WHERE id = ?",
+			prefix: "$",
+			syntax: PostgresSyntax,
+			want:   "UPDATE users SET name = $1 WHERE id = $2",
+		},
+		{
+			name:   "at",
+			query:  "SELECT * FROM users WHERE id IN (?, ?)",
+			prefix: "@p",
+			syntax: StandardSyntax,
+			want:   "SELECT * FROM users WHERE id IN (@p1, @p2)",
+		},
+		{
+			name:   "literals and comments",
+			query:  "SELECT '?', \"?\" FROM t /* ? */ WHERE id = ? -- ?",
+			prefix: "$",
+			syntax: PostgresSyntax,
+			want:   "SELECT '?', \"?\" FROM t /* ? */ WHERE id = $1 -- ?",
+		},
+		{
+			name:   "backslash ends a postgres string",
+			query:  `SELECT * FROM files WHERE path = 'C:\' AND id = ?`,
+			prefix: "$",
+			syntax: PostgresSyntax,
+			want:   `SELECT * FROM files WHERE path = 'C:\' AND id = $1`,
+		},
+		{
+			name:   "backslash escapes a postgres E string",
+			query:  `SELECT E'it\'s ?', ? FROM t`,
+			prefix: "$",
+			syntax: PostgresSyntax,
+			want:   `SELECT E'it\'s ?', $1 FROM t`,
+		},
+		{
+			name:   "dollar quoted strings",
+			query:  "SELECT $$ a ? $$, $fn$ b ? $fn$, $1x FROM t WHERE id = ?",
+			prefix: "$",
+			syntax: PostgresSyntax,
+			want:   "SELECT $$ a ? $$, $fn$ b ? $fn$, $1x FROM t WHERE id = $1",
+		},
+		{
+			name:   "backslash escapes a mysql string",
+			query:  `SELECT 'it\'s ?' FROM t WHERE id = ?`,
+			prefix: "$",
+			syntax: MySQLSyntax,
+			want:   `SELECT 'it\'s ?' FROM t WHERE id = $1`,
+		},
+		{
+			name:   "no placeholders",
+			query:  "SELECT * FROM users",
+			prefix: "$",
+			syntax: PostgresSyntax,
+			want:   "SELECT * FROM users",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rebind(tt.query, tt.prefix, tt.syntax); got != tt.want {
+				t.Errorf("Rebind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package internal
+
+import "strings"
+
+// TokenKind is the kind of a SQL token.
+type TokenKind int
+
+const (
+	TokenWord    TokenKind = iota // Keyword, identifier or number.
+	TokenQuoted                   // Quoted identifier: `name`, "name" or [name].
+	TokenString                   // String literal: 'text', E'text' or $tag$text$tag$.
+	TokenComment                  // Comment: -- text or /* text */.
+	TokenSpace                    // Whitespace.
+	TokenPunct                    // Any other single character: ( ) , . * ? : ; = ...
+)
+
+// Token is a piece of a SQL query. Joining the text of every token returns the original query.
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// Is reports whether the token is the given word or punctuation, ignoring case.
+func (t Token) Is(text string) bool {
+	return (t.Kind == TokenWord || t.Kind == TokenPunct) && strings.EqualFold(t.Text, text)
+}
+
+// Syntax describes how the string literals of a SQL dialect are quoted.
+type Syntax struct {
+	BackslashEscapes bool // A backslash escapes the next character of any string, as in MySQL.
+	EscapeStrings    bool // A backslash escapes the next character of E'' strings, as in Postgres.
+	DollarQuotes     bool // $tag$ ... $tag$ quotes a string, as in Postgres.
+}
+
+// Syntaxes of the supported dialects. SQLite and SQL Server only escape a quote by doubling it.
+var (
+	MySQLSyntax    = Syntax{BackslashEscapes: true}
+	PostgresSyntax = Syntax{EscapeStrings: true, DollarQuotes: true}
+	StandardSyntax = Syntax{}
+)
+
+// DialectSyntax returns the syntax of the dialect with the name, as returned by rdbx.Dialect.String.
+func DialectSyntax(name string) Syntax {
+	switch name {
+	case "mysql":
+		return MySQLSyntax
+	case "postgres":
+		return PostgresSyntax
+	default:
+		return StandardSyntax
+	}
+}
+
+// Tokenize splits a SQL query into tokens with the syntax of MySQL, the default dialect.
+func Tokenize(query string) []Token {
+	return TokenizeSyntax(query, MySQLSyntax)
+}
+
+// TokenizeSyntax splits a SQL query into tokens. It does not validate the query, an
+// unterminated literal or comment simply runs to the end of the query.
+func TokenizeSyntax(query string, syntax Syntax) []Token {
+	var tokens []Token
+
+	for i := 0; i < len(query); {
+		kind, end := scanToken(query, i, syntax)
+		tokens = append(tokens, Token{Kind: kind, Text: query[i:end]})
+		i = end
+	}
+
+	return tokens
+}
+
+// JoinTokens returns the query the tokens were read from.
+func JoinTokens(tokens []Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.Text)
+	}
+
+	return b.String()
+}
+
+// Significant returns the tokens that are neither whitespace nor comments.
+func Significant(tokens []Token) []Token {
+	sig := make([]Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Kind != TokenSpace && t.Kind != TokenComment {
+			sig = append(sig, t)
+		}
+	}
+
+	return sig
+}
+
+// scanToken returns the kind and end offset of the token starting at i.
+func scanToken(query string, i int, syntax Syntax) (TokenKind, int) {
+	c := query[i]
+
+	switch {
+	case isSpace(c):
+		end := i + 1
+		for end < len(query) && isSpace(query[end]) {
+			end++
+		}
+
+		return TokenSpace, end
+	case c == '-' && strings.HasPrefix(query[i:], "--"):
+		end := strings.IndexByte(query[i:], '\n')
+		if end < 0 {
+			return TokenComment, len(query)
+		}
+
+		return TokenComment, i + end + 1
+	case c == '/' && strings.HasPrefix(query[i:], "/*"):
+		end := strings.Index(query[i+2:], "*/")
+		if end < 0 {
+			return TokenComment, len(query)
+		}
+
+		return TokenComment, i + 2 + end + 2
+	case c == '\'':
+		return TokenString, scanQuoted(query, i, '\'', syntax.BackslashEscapes)
+	case c == '"' || c == '`':
+		return TokenQuoted, scanQuoted(query, i, c, false)
+	case c == '[':
+		return TokenQuoted, scanQuoted(query, i, ']', false)
+	case (c == 'E' || c == 'e') && syntax.EscapeStrings && strings.HasPrefix(query[i+1:], "'"):
+		return TokenString, scanQuoted(query, i+1, '\'', true)
+	case c == '$' && syntax.DollarQuotes && dollarTag(query[i:]) != "":
+		tag := dollarTag(query[i:])
+
+		end := strings.Index(query[i+len(tag):], tag)
+		if end < 0 {
+			return TokenString, len(query)
+		}
+
+		return TokenString, i + len(tag) + end + len(tag)
+	case isWordChar(c):
+		end := i + 1
+		for end < len(query) && isWordChar(query[end]) {
+			end++
+		}
+
+		return TokenWord, end
+	default:
+		return TokenPunct, i + 1
+	}
+}
+
+// scanQuoted returns the end offset of a quoted token starting at i. A doubled
+// closing quote is an escaped quote, and so is a backslash when backslashes escape.
+func scanQuoted(query string, i int, closing byte, backslashEscapes bool) int {
+	for end := i + 1; end < len(query); end++ {
+		switch query[end] {
+		case '\\':
+			if backslashEscapes {
+				end++
+			}
+		case closing:
+			if end+1 < len(query) && query[end+1] == closing {
+				end++
+				continue
+			}
+
+			return end + 1
+		}
+	}
+
+	return len(query)
+}
+
+// dollarTag returns the $tag$ that opens a dollar-quoted string at the start of s, or "".
+// The tag is an identifier that does not start with a digit, so "$1" is not a tag.
+func dollarTag(s string) string {
+	for end := 1; end < len(s); end++ {
+		c := s[end]
+
+		switch {
+		case c == '$':
+			return s[:end+1]
+		case c >= '0' && c <= '9':
+			if end == 1 {
+				return ""
+			}
+		case c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && c < 0x80:
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
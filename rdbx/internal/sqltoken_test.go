@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []Token
+	}{
+		{
+			name:  "words and punctuation",
+			query: "SELECT a.*, COUNT(*) FROM t",
+			want: []Token{
+				{TokenWord, "SELECT"}, {TokenSpace, " "}, {TokenWord, "a"}, {TokenPunct, "."}, {TokenPunct, "*"},
+				{TokenPunct, ","}, {TokenSpace, " "}, {TokenWord, "COUNT"}, {TokenPunct, "("}, {TokenPunct, "*"},
+				{TokenPunct, ")"}, {TokenSpace, " "}, {TokenWord, "FROM"}, {TokenSpace, " "}, {TokenWord, "t"},
+			},
+		},
+		{
+			name:  "literals, quoted identifiers and comments",
+			query: "'it''s * \\' x' `a``b` \"c\" [d] /* * */ -- *\n",
+			want: []Token{
+				{TokenString, "'it''s * \\' x'"}, {TokenSpace, " "}, {TokenQuoted, "`a``b`"}, {TokenSpace, " "},
+				{TokenQuoted, "\"c\""}, {TokenSpace, " "}, {TokenQuoted, "[d]"}, {TokenSpace, " "},
+				{TokenComment, "/* * */"}, {TokenSpace, " "}, {TokenComment, "-- *\n"},
+			},
+		},
+		{
+			name:  "unterminated",
+			query: "SELECT 'abc",
+			want:  []Token{{TokenWord, "SELECT"}, {TokenSpace, " "}, {TokenString, "'abc"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tokenize(tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize() = %v, want %v", got, tt.want)
+			}
+
+			if q := JoinTokens(got); q != tt.query {
+				t.Errorf("JoinTokens() = %v, want %v", q, tt.query)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import "strings"
+
+// tableListEnd are the words that cannot be a table alias, they end a table reference.
+var tableListEnd = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "full": true,
+	"cross": true, "outer": true, "natural": true, "straight_join": true, "on": true,
+	"using": true, "group": true, "order": true, "having": true, "limit": true,
+	"offset": true, "union": true, "except": true, "intersect": true, "for": true,
+	"set": true, "values": true, "value": true, "select": true, "returning": true,
+	"window": true, "lock": true, "partition": true, "default": true,
+}
+
+// tableModifiers are the words that may sit between a keyword and the table names it introduces,
+// such as UPDATE IGNORE users, INSERT LOW_PRIORITY INTO users or TRUNCATE TABLE ONLY users.
+var tableModifiers = map[string]map[string]bool{
+	"from":     {"only": true},
+	"update":   {"low_priority": true, "ignore": true, "only": true},
+	"insert":   {"low_priority": true, "delayed": true, "high_priority": true, "ignore": true, "into": true},
+	"truncate": {"table": true, "only": true},
+}
+
+// Tables returns the lowercased names of the tables a query reads from or writes to,
+// found after FROM, JOIN, UPDATE, INSERT, INTO and TRUNCATE. Schema qualifiers are dropped, so
+// "app.users" is reported as "users". The result may contain names that are not
+// tables, it is meant for cache invalidation where a false positive is harmless.
+func Tables(query string) []string {
+	tokens := Significant(Tokenize(query))
+
+	var tables []string
+	seen := map[string]bool{}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		switch {
+		case t.Is("from"), t.Is("join"), t.Is("into"), t.Is("insert"), t.Is("truncate"):
+		case t.Is("update"):
+			// SELECT ... FOR UPDATE and ON DUPLICATE KEY UPDATE do not name a table.
+			if i > 0 && (tokens[i-1].Is("for") || tokens[i-1].Is("key")) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		list := t.Is("from") || t.Is("truncate")
+		modifiers := tableModifiers[strings.ToLower(t.Text)]
+
+		i++
+		for i < len(tokens) && tokens[i].Kind == TokenWord && modifiers[strings.ToLower(tokens[i].Text)] {
+			i++
+		}
+
+		for i < len(tokens) {
+			name, next, ok := readTableName(tokens, i)
+			if !ok {
+				break
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				tables = append(tables, name)
+			}
+
+			i = skipAlias(tokens, next)
+
+			if !list || i >= len(tokens) || !tokens[i].Is(",") {
+				break
+			}
+
+			i++
+		}
+
+		i--
+	}
+
+	return tables
+}
+
+// readTableName reads a possibly qualified name starting at i and returns its last part.
+func readTableName(tokens []Token, i int) (string, int, bool) {
+	var name string
+
+	for i < len(tokens) {
+		t := tokens[i]
+		if t.Kind != TokenWord && t.Kind != TokenQuoted {
+			break
+		}
+
+		if t.Kind == TokenWord && tableListEnd[strings.ToLower(t.Text)] {
+			break
+		}
+
+		name = Unquote(t.Text)
+		i++
+
+		if i < len(tokens) && tokens[i].Is(".") {
+			i++
+			continue
+		}
+
+		return strings.ToLower(name), i, true
+	}
+
+	return "", i, false
+}
+
+// skipAlias skips an optional "AS alias" or "alias" after a table name.
+func skipAlias(tokens []Token, i int) int {
+	if i < len(tokens) && tokens[i].Is("as") {
+		i++
+	}
+
+	if i < len(tokens) {
+		t := tokens[i]
+		if t.Kind == TokenQuoted || t.Kind == TokenWord && !tableListEnd[strings.ToLower(t.Text)] {
+			i++
+		}
+	}
+
+	return i
+}
+
+// Unquote removes the quotes around a quoted identifier.
+func Unquote(ident string) string {
+	if len(ident) < 2 {
+		return ident
+	}
+
+	switch ident[0] {
+	case '`', '"':
+		if ident[len(ident)-1] == ident[0] {
+			return strings.ReplaceAll(ident[1:len(ident)-1], ident[:1]+ident[:1], ident[:1])
+		}
+	case '[':
+		if ident[len(ident)-1] == ']' {
+			return ident[1 : len(ident)-1]
+		}
+	}
+
+	return ident
+}
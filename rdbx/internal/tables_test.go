@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "select",
+			query: "SELECT * FROM users WHERE id = ?",
+			want:  []string{"users"},
+		},
+		{
+			name:  "join with aliases and schema",
+			query: "SELECT u.id FROM app.Users AS u LEFT JOIN `orders` o ON o.user_id = u.id",
+			want:  []string{"users", "orders"},
+		},
+		{
+			name:  "comma join",
+			query: "SELECT * FROM users u, orders o, \"payments\" WHERE u.id = o.user_id",
+			want:  []string{"users", "orders", "payments"},
+		},
+		{
+			name:  "subquery",
+			query: "SELECT * FROM (SELECT id FROM users) x JOIN orders ON orders.user_id = x.id",
+			want:  []string{"users", "orders"},
+		},
+		{
+			name:  "update",
+			query: "UPDATE users SET name = ? WHERE id = ?",
+			want:  []string{"users"},
+		},
+		{
+			name:  "insert upsert",
+			query: "INSERT INTO users (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name)",
+			want:  []string{"users"},
+		},
+		{
+			name:  "delete",
+			query: "DELETE FROM users WHERE id = ?",
+			want:  []string{"users"},
+		},
+		{
+			name:  "locking read",
+			query: "SELECT * FROM balances WHERE id = ? FOR UPDATE",
+			want:  []string{"balances"},
+		},
+		{
+			name:  "literals and comments are ignored",
+			query: "SELECT 'from accounts' FROM users /* join orders */ -- from payments\nWHERE 1 = 1",
+			want:  []string{"users"},
+		},
+		{
+			name:  "update ignore",
+			query: "UPDATE IGNORE users SET name = ? WHERE id = ?",
+			want:  []string{"users"},
+		},
+		{
+			name:  "update low priority",
+			query: "UPDATE LOW_PRIORITY IGNORE users SET name = ?",
+			want:  []string{"users"},
+		},
+		{
+			name:  "update only",
+			query: "UPDATE ONLY users SET name = $1",
+			want:  []string{"users"},
+		},
+		{
+			name:  "insert modifiers",
+			query: "INSERT LOW_PRIORITY IGNORE INTO users (id) VALUES (?)",
+			want:  []string{"users"},
+		},
+		{
+			name:  "insert without into",
+			query: "INSERT DELAYED users (id) VALUES (?)",
+			want:  []string{"users"},
+		},
+		{
+			name:  "delete modifiers",
+			query: "DELETE LOW_PRIORITY QUICK IGNORE FROM users WHERE id = ?",
+			want:  []string{"users"},
+		},
+		{
+			name:  "delete only",
+			query: "DELETE FROM ONLY users WHERE id = $1",
+			want:  []string{"users"},
+		},
+		{
+			name:  "truncate",
+			query: "TRUNCATE users",
+			want:  []string{"users"},
+		},
+		{
+			name:  "truncate table",
+			query: "TRUNCATE TABLE app.users",
+			want:  []string{"users"},
+		},
+		{
+			name:  "truncate several tables",
+			query: "TRUNCATE TABLE ONLY users, orders RESTART IDENTITY",
+			want:  []string{"users", "orders"},
+		},
+		{
+			name:  "no table",
+			query: "SELECT 1",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tables(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
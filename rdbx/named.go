@@ -0,0 +1,115 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// bindNamed rewrites the ":name" parameters of the query to positional placeholders and returns
+// the matching arguments, read from arg: a struct with column tags, or a map with string keys.
+func bindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	query, names := internal.CompileNamed(query)
+	if len(names) == 0 {
+		return query, nil, nil
+	}
+
+	lookup, err := namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		v, ok := lookup(name)
+		if !ok {
+			return "", nil, fmt.Errorf("rdbx: named parameter %q not found in %T", name, arg)
+		}
+
+		args[i] = v
+	}
+
+	return query, args, nil
+}
+
+// namedLookup returns a function that reads the value of a named parameter from arg.
+func namedLookup(arg interface{}) (func(name string) (interface{}, bool), error) {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		return func(name string) (interface{}, bool) {
+			mv := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !mv.IsValid() {
+				return nil, false
+			}
+
+			return mv.Interface(), true
+		}, nil
+	case v.Kind() == reflect.Struct:
+		mapping := internal.MappingOf(v.Type())
+
+		return func(name string) (interface{}, bool) {
+			f, ok := mapping.Field(name)
+			if !ok {
+				return nil, false
+			}
+
+			fv, ok := fieldValue(v, f.Index)
+			if !ok {
+				return nil, true
+			}
+
+			return fv.Interface(), true
+		}, nil
+	default:
+		return nil, fmt.Errorf("rdbx: cannot bind named parameters from %T, want a struct or a map", arg)
+	}
+}
+
+// fieldValue returns the field of the struct value at the index path. It reports false when
+// the path goes through a nil pointer, the field is then NULL.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+// NamedExecContext executes a query with ":name" parameters, such as
+// "UPDATE users SET name = :name WHERE id = :id". The parameters are read from arg,
+// a struct with column tags or a map[string]interface{}.
+func (x *dbx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	query, args, err := bindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return x.ExecContext(ctx, query, args...)
+}
+
+// NamedQueryx is Queryx with ":name" parameters read from arg, a struct with column tags
+// or a map[string]interface{}.
+func (x *dbx) NamedQueryx(ctx context.Context, query string, model interface{}, arg interface{}) error {
+	query, args, err := bindNamed(query, arg)
+	if err != nil {
+		return err
+	}
+
+	return x.Queryx(ctx, query, model, args...)
+}
@@ -0,0 +1,90 @@
+package rdbx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bindNamed(t *testing.T) {
+	type audit struct {
+		UpdatedBy string `column:"updated_by"`
+	}
+
+	type user struct {
+		ID    int64   `column:"id"`
+		Name  string  `column:"name"`
+		Email *string `column:"email"`
+		*audit
+	}
+
+	query, args, err := bindNamed("UPDATE users SET name = :name, email = :email WHERE id = :id", user{ID: 1, Name: "alie"})
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET name = ?, email = ? WHERE id = ?", query)
+	assert.Equal(t, []interface{}{"alie", (*string)(nil), int64(1)}, args)
+
+	_, args, err = bindNamed("UPDATE users SET updated_by = :updated_by", &user{})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{nil}, args)
+
+	_, args, err = bindNamed("UPDATE users SET updated_by = :updated_by", &user{audit: &audit{UpdatedBy: "bob"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"bob"}, args)
+
+	_, args, err = bindNamed("SELECT * FROM users WHERE id = :id", map[string]interface{}{"id": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{2}, args)
+
+	_, _, err = bindNamed("SELECT * FROM users WHERE id = :uid", user{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `"uid"`)
+	}
+
+	_, _, err = bindNamed("SELECT * FROM users WHERE id = :id", 1)
+	assert.Error(t, err)
+
+	query, args, err = bindNamed("SELECT * FROM users", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users", query)
+	assert.Nil(t, args)
+}
+
+func Test_dbx_Named(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID   int64  `column:"id"`
+		Name string `column:"name"`
+	}
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil)
+
+	mock.ExpectExec("UPDATE users SET name=? WHERE id=?").
+		WithArgs("alie", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	res, err := dbx.NamedExecContext(ctx, "UPDATE users SET name=:name WHERE id=:id", user{ID: 1, Name: "alie"})
+	assert.NoError(t, err)
+
+	affected, err := res.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE name = ?").
+		WithArgs("alie").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alie"))
+
+	var users []user
+	err = dbx.NamedQueryx(ctx, "SELECT * FROM users WHERE name = :name", &users, map[string]interface{}{"name": "alie"})
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "alie"}}, users)
+
+	_, err = dbx.NamedExecContext(ctx, "DELETE FROM users WHERE id = :id", map[string]interface{}{})
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,360 @@
+package rdbx
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// ErrInvalidCursor is returned by Paginate and PaginateKeyset for a cursor they did not issue.
+var ErrInvalidCursor = errors.New("rdbx: invalid page cursor")
+
+// Page is a page of the rows of a query.
+type Page[T any] struct {
+	Items []T
+
+	// NextCursor is the cursor of the next page, empty on the last page.
+	NextCursor string
+
+	// Total is the number of rows of the query across all pages, set with WithTotalCount.
+	Total *int64
+}
+
+// PageOption represents an option of Paginate and PaginateKeyset.
+type PageOption interface {
+	Apply(*pageOptions)
+}
+
+// pageOptions holds the options of a page.
+type pageOptions struct {
+	total bool
+}
+
+// pageOptionFunc represents a function that applies an option to a page.
+type pageOptionFunc func(*pageOptions)
+
+// Apply applies the option to the page.
+func (f pageOptionFunc) Apply(o *pageOptions) {
+	f(o)
+}
+
+// WithTotalCount returns an option that counts the rows of the query across all pages into Page.Total,
+// at the cost of a COUNT query.
+func WithTotalCount() PageOption {
+	return pageOptionFunc(func(o *pageOptions) {
+		o.total = true
+	})
+}
+
+// pageCursor is the content of an opaque page cursor.
+type pageCursor struct {
+	Offset int               `json:"o,omitempty"`
+	Keys   []json.RawMessage `json:"k,omitempty"`
+}
+
+// encodeCursor returns the opaque form of the cursor.
+func encodeCursor(c pageCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor returns the content of an opaque cursor, the zero cursor of the first page for "".
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	if s == "" {
+		return c, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil || c.Offset < 0 {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Paginate returns a page of size rows of the query, skipping the rows of the previous pages
+// with OFFSET. The cursor is the NextCursor of the previous page, empty for the first page.
+// The query needs an ORDER BY that orders rows the same way every time, such as one ending
+// with the primary key. For deep pages prefer PaginateKeyset, which does not scan the skipped rows.
+// The query is written in the dialect of db, whatever the dialect of the builder.
+func Paginate[T any](
+	ctx context.Context,
+	db DBTX,
+	q *SelectBuilder,
+	cursor string,
+	size int,
+	options ...PageOption,
+) (*Page[T], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("rdbx: invalid page size %d", size)
+	}
+
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Keys != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	q = q.Dialect(dialectOf(db))
+
+	// One more row than the page tells whether there is a next page.
+	query, args := q.Limit(size + 1).Offset(c.Offset).ToSQL()
+
+	items, err := QueryAll[T](ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items}
+
+	if len(items) > size {
+		page.Items = items[:size]
+
+		if page.NextCursor, err = encodeCursor(pageCursor{Offset: c.Offset + size}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := countPage(ctx, db, q, page, options); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// SortKey is a column of the ordering of PaginateKeyset.
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Asc returns the key that orders the column in ascending order.
+func Asc(column string) SortKey {
+	return SortKey{Column: column}
+}
+
+// Desc returns the key that orders the column in descending order.
+func Desc(column string) SortKey {
+	return SortKey{Column: column, Desc: true}
+}
+
+// PaginateKeyset returns a page of size rows of the query, ordered by the keys. The next page
+// starts after the key values of the last row, e.g. WHERE (created_at, id) > (?, ?), so deep pages
+// cost as much as the first one with an index on the keys. The cursor is the NextCursor of the
+// previous page, empty for the first page.
+//
+// PaginateKeyset orders the query by the keys, an ordered query is an error. The key columns must not be
+// NULL, must be mapped to fields of T, and the last one must be unique, such as the primary key.
+// As with Paginate, the query is written in the dialect of db.
+func PaginateKeyset[T any](
+	ctx context.Context,
+	db DBTX,
+	q *SelectBuilder,
+	keys []SortKey,
+	cursor string,
+	size int,
+	options ...PageOption,
+) (*Page[T], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("rdbx: invalid page size %d", size)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("rdbx: keyset pagination needs sort keys")
+	}
+
+	if len(q.orderBy) > 0 {
+		return nil, errors.New("rdbx: keyset pagination orders the query by its sort keys, remove its ORDER BY")
+	}
+
+	var zero T
+
+	elType := reflect.TypeOf(zero)
+	if elType == nil || elType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rdbx: cannot scan rows into %T, want a struct", zero)
+	}
+
+	fields, err := keyFields(elType, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Offset != 0 || c.Keys != nil && len(c.Keys) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	q = q.Dialect(dialectOf(db))
+	base := q
+
+	if c.Keys != nil {
+		values := make([]interface{}, len(keys))
+		for i, f := range fields {
+			v := reflect.New(elType.FieldByIndex(f.Index).Type)
+			if err := json.Unmarshal(c.Keys[i], v.Interface()); err != nil {
+				return nil, ErrInvalidCursor
+			}
+
+			values[i] = v.Elem().Interface()
+		}
+
+		q = q.Where(keysetCond(keys, values, q.dialect))
+	}
+
+	orderBy := make([]string, len(keys))
+	for i, k := range keys {
+		orderBy[i] = k.Column
+		if k.Desc {
+			orderBy[i] += " DESC"
+		}
+	}
+
+	query, args := q.OrderBy(orderBy...).Limit(size + 1).ToSQL()
+
+	items, err := QueryAll[T](ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items}
+
+	if len(items) > size {
+		page.Items = items[:size]
+
+		last := reflect.ValueOf(page.Items[size-1])
+
+		next := pageCursor{Keys: make([]json.RawMessage, len(fields))}
+		for i, f := range fields {
+			var value interface{}
+			if v, ok := fieldValue(last, f.Index); ok {
+				value = v.Interface()
+			}
+
+			if next.Keys[i], err = json.Marshal(value); err != nil {
+				return nil, err
+			}
+		}
+
+		if page.NextCursor, err = encodeCursor(next); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := countPage(ctx, db, base, page, options); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// keyFields returns the fields of the struct type the sort keys are read from. A qualified column
+// such as "u.id" is mapped by its name without the qualifier.
+func keyFields(t reflect.Type, keys []SortKey) ([]*internal.Field, error) {
+	mapping := internal.MappingOf(t)
+
+	fields := make([]*internal.Field, len(keys))
+	for i, k := range keys {
+		column := k.Column
+		if j := strings.LastIndexByte(column, '.'); j >= 0 {
+			column = column[j+1:]
+		}
+
+		f, ok := mapping.Field(column)
+		if !ok {
+			return nil, fmt.Errorf("rdbx: sort key %q is not a column of %s", k.Column, t)
+		}
+
+		fields[i] = f
+	}
+
+	return fields, nil
+}
+
+// keysetCond returns the condition of the rows after the key values. When every key has the
+// same direction it compares row values, (a, b) > (?, ?), which SQL Server lacks; otherwise it
+// compares key by key: a > ? OR (a = ? AND b < ?).
+func keysetCond(keys []SortKey, values []interface{}, d Dialect) Cond {
+	op := func(k SortKey) string {
+		if k.Desc {
+			return "<"
+		}
+
+		return ">"
+	}
+
+	sameDirection := true
+	for _, k := range keys[1:] {
+		sameDirection = sameDirection && k.Desc == keys[0].Desc
+	}
+
+	if sameDirection && d != SQLServerDialect {
+		columns := make([]string, len(keys))
+		for i, k := range keys {
+			columns[i] = k.Column
+		}
+
+		placeholders := strings.Repeat("?, ", len(keys)-1) + "?"
+
+		return Cond{
+			sql:  "(" + strings.Join(columns, ", ") + ") " + op(keys[0]) + " (" + placeholders + ")",
+			args: values,
+		}
+	}
+
+	or := make([]Cond, len(keys))
+	for i, k := range keys {
+		and := make([]Cond, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, compare(keys[j].Column, "=", values[j]))
+		}
+
+		or[i] = And(append(and, compare(k.Column, op(k), values[i]))...)
+	}
+
+	return Or(or...)
+}
+
+// countPage counts the rows of the query into the page when the options ask for it.
+func countPage[T any](ctx context.Context, db DBTX, q *SelectBuilder, page *Page[T], options []PageOption) error {
+	var opts pageOptions
+	for _, o := range options {
+		o.Apply(&opts)
+	}
+
+	if !opts.total {
+		return nil
+	}
+
+	query, args := q.count().ToSQL()
+
+	var total int64
+	if err := queryScalar(ctx, db, query, &total, args...); err != nil {
+		return err
+	}
+
+	page.Total = &total
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package rdbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID   int64  `column:"id"`
+		Name string `column:"name"`
+	}
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil)
+	q := Select().From("users").Where(Eq("status", "active")).OrderBy("id")
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE status = ? ORDER BY id LIMIT 3").
+		WithArgs("active").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b").AddRow(3, "c"))
+	mock.ExpectQuery("SELECT COUNT(*) FROM users WHERE status = ?").
+		WithArgs("active").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	page, err := Paginate[user](ctx, dbx, q, "", 2, WithTotalCount())
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, page.Items)
+	assert.NotEmpty(t, page.NextCursor)
+	if assert.NotNil(t, page.Total) {
+		assert.Equal(t, int64(3), *page.Total)
+	}
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE status = ? ORDER BY id LIMIT 3 OFFSET 2").
+		WithArgs("active").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "c"))
+
+	page, err = Paginate[user](ctx, dbx, q, page.NextCursor, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 3, Name: "c"}}, page.Items)
+	assert.Empty(t, page.NextCursor)
+	assert.Nil(t, page.Total)
+
+	_, err = Paginate[user](ctx, dbx, q, "not a cursor", 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaginateKeyset(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID        int64     `column:"id"`
+		CreatedAt time.Time `column:"created_at"`
+	}
+
+	ctx := context.Background()
+	q := Select().From("users")
+
+	t1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+
+	t.Run("row values", func(t *testing.T) {
+		dbx := NewDbx(db, nil)
+		keys := []SortKey{Asc("created_at"), Asc("id")}
+
+		mock.ExpectQuery("SELECT id, created_at FROM users ORDER BY created_at, id LIMIT 3").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, t1).AddRow(2, t2).AddRow(3, t2))
+
+		page, err := PaginateKeyset[user](ctx, dbx, q, keys, "", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []user{{ID: 1, CreatedAt: t1}, {ID: 2, CreatedAt: t2}}, page.Items)
+
+		mock.ExpectQuery("SELECT id, created_at FROM users WHERE (created_at, id) > (?, ?) ORDER BY created_at, id LIMIT 3").
+			WithArgs(t2, int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, t2))
+
+		page, err = PaginateKeyset[user](ctx, dbx, q, keys, page.NextCursor, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []user{{ID: 3, CreatedAt: t2}}, page.Items)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("mixed directions", func(t *testing.T) {
+		dbx := NewDbx(db, nil, WithDialect(PostgresDialect))
+		keys := []SortKey{Desc("created_at"), Asc("id")}
+
+		mock.ExpectQuery("SELECT id, created_at FROM users ORDER BY created_at DESC, id LIMIT 2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, t2).AddRow(3, t2))
+
+		page, err := PaginateKeyset[user](ctx, dbx, q, keys, "", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []user{{ID: 2, CreatedAt: t2}}, page.Items)
+
+		mock.ExpectQuery("SELECT id, created_at FROM users WHERE (created_at < $1 OR (created_at = $2 AND id > $3)) "+
+			"ORDER BY created_at DESC, id LIMIT 2").
+			WithArgs(t2, t2, int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, t2))
+
+		_, err = PaginateKeyset[user](ctx, dbx, q, keys, page.NextCursor, 1)
+		assert.NoError(t, err)
+
+		_, err = PaginateKeyset[user](ctx, dbx, q, []SortKey{Asc("email")}, "", 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("ordered query", func(t *testing.T) {
+		_, err := PaginateKeyset[user](ctx, NewDbx(db, nil), q.OrderBy("name"), []SortKey{Asc("id")}, "", 1)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "ORDER BY")
+		}
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,74 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// scanConfigurer is implemented by the DBTX that configure how rows are scanned, such as dbx.
+type scanConfigurer interface {
+	scanConfig() scanOptions
+}
+
+// scanConfig returns how Queryx and the generic query functions scan rows.
+func (x *dbx) scanConfig() scanOptions {
+	return scanOptions{zeroOnNull: x.zeroOnNull}
+}
+
+// QueryAll runs the query and returns its rows as a slice of T, a struct with column tags.
+// A "*" in the query is expanded to the columns of T, as with Queryx.
+// The result is served from the cache like QueryContext.
+func QueryAll[T any](ctx context.Context, db DBTX, query string, args ...interface{}) ([]T, error) {
+	var rows []T
+
+	if err := queryInto(ctx, db, query, &rows, args...); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// QueryOne runs the query and returns its first row as a T, a struct with column tags.
+// It returns sql.ErrNoRows when the query returns no row.
+func QueryOne[T any](ctx context.Context, db DBTX, query string, args ...interface{}) (T, error) {
+	var zero T
+
+	rows, err := QueryAll[T](ctx, db, query, args...)
+	if err != nil {
+		return zero, err
+	}
+
+	if len(rows) == 0 {
+		return zero, sql.ErrNoRows
+	}
+
+	return rows[0], nil
+}
+
+// queryInto runs the query and scans its rows into dest, a pointer to a slice of structs.
+func queryInto(ctx context.Context, db DBTX, query string, dest interface{}, args ...interface{}) error {
+	v := reflect.ValueOf(dest).Elem()
+
+	if elType := v.Type().Elem(); elType.Kind() != reflect.Struct {
+		return fmt.Errorf("rdbx: cannot scan rows into %s, want a struct", elType)
+	}
+
+	query = internal.ModifyOrKeepField(query, dest)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var opts scanOptions
+	if c, ok := db.(scanConfigurer); ok {
+		opts = c.scanConfig()
+	}
+
+	return scanRows(rows, v, opts)
+}
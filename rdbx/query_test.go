@@ -0,0 +1,75 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryAll(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID       int64  `column:"id"`
+		Nickname string `column:"nickname"`
+	}
+
+	ctx := context.Background()
+	dbx := NewDbx(db, newMapCache())
+
+	mock.ExpectQuery("SELECT id, nickname FROM users WHERE active = ?").
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "nickname"}).AddRow(1, "alie").AddRow(2, nil))
+
+	_, err = QueryAll[user](ctx, dbx, "SELECT * FROM users WHERE active = ?", true)
+	assert.ErrorIs(t, err, errNullValue)
+
+	mock.ExpectQuery("SELECT id, nickname FROM users WHERE active = ?").
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "nickname"}).AddRow(1, "alie").AddRow(2, nil))
+
+	users, err := QueryAll[user](ctx, NewDbx(db, nil, WithZeroOnNull()), "SELECT * FROM users WHERE active = ?", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Nickname: "alie"}, {ID: 2}}, users)
+
+	_, err = QueryAll[int64](ctx, dbx, "SELECT id FROM users")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryOne(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID       int64  `column:"id"`
+		Nickname string `column:"nickname"`
+	}
+
+	ctx := context.Background()
+	dbx := NewDbx(db, newMapCache())
+
+	mock.ExpectQuery("SELECT id, nickname FROM users WHERE id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "nickname"}).AddRow(1, "alie"))
+
+	for i := 0; i < 2; i++ {
+		u, err := QueryOne[user](ctx, dbx, "SELECT * FROM users WHERE id = ?", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, user{ID: 1, Nickname: "alie"}, u)
+	}
+
+	mock.ExpectQuery("SELECT id, nickname FROM users WHERE id = ?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "nickname"}))
+
+	_, err = QueryOne[user](ctx, dbx, "SELECT * FROM users WHERE id = ?", 2)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,161 @@
+package rdbx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// Repository reads and writes the rows of a table as values of T, a struct with column tags.
+// The columns tagged with the pk option identify a row for Get, Update and Delete, and the
+// other tag options control the written columns as described on Tabler.
+//
+// A Repository runs its queries on its DBTX: with a dbx, reads are served from the cache like
+// QueryContext, writes invalidate the cached results of the table, and inside a transaction
+// started by EnableTx every query runs in the transaction of the context.
+type Repository[T any] struct {
+	db    DBTX
+	table string
+}
+
+// NewRepository returns a Repository of the rows of the table.
+func NewRepository[T any](db DBTX, table string) *Repository[T] {
+	return &Repository[T]{db: db, table: table}
+}
+
+// Get returns the row with the pk values, given in the order of the pk fields of T.
+// It returns sql.ErrNoRows when there is no such row.
+func (r *Repository[T]) Get(ctx context.Context, keys ...interface{}) (T, error) {
+	conds, err := r.keyConds(keys)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	where, args := whereClause(conds)
+
+	return QueryOne[T](ctx, r.db, "SELECT * FROM "+r.table+where, args...)
+}
+
+// List returns the rows that match every condition, or every row without conditions.
+func (r *Repository[T]) List(ctx context.Context, conds ...Cond) ([]T, error) {
+	where, args := whereClause(conds)
+
+	return QueryAll[T](ctx, r.db, "SELECT * FROM "+r.table+where, args...)
+}
+
+// Create inserts the model. With MySQL and SQLite, its zero autoincrement field is set
+// to the id of the inserted row.
+func (r *Repository[T]) Create(ctx context.Context, model *T) error {
+	if model == nil {
+		return fmt.Errorf("rdbx: cannot create a nil %T", model)
+	}
+
+	_, err := insertStruct(ctx, r.db, r.table, reflect.ValueOf(model).Elem())
+
+	return err
+}
+
+// Update updates the row of the model, found by its pk values.
+func (r *Repository[T]) Update(ctx context.Context, model T) error {
+	_, err := updateStruct(ctx, r.db, r.table, reflect.ValueOf(&model).Elem())
+
+	return err
+}
+
+// Delete deletes the row with the pk values, given in the order of the pk fields of T.
+func (r *Repository[T]) Delete(ctx context.Context, keys ...interface{}) error {
+	conds, err := r.keyConds(keys)
+	if err != nil {
+		return err
+	}
+
+	where, args := whereClause(conds)
+
+	_, err = r.db.ExecContext(ctx, "DELETE FROM "+r.table+where, args...)
+
+	return err
+}
+
+// Count returns the number of rows that match every condition.
+func (r *Repository[T]) Count(ctx context.Context, conds ...Cond) (int64, error) {
+	where, args := whereClause(conds)
+
+	var n int64
+	if err := queryScalar(ctx, r.db, "SELECT COUNT(*) FROM "+r.table+where, &n, args...); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Exists reports whether a row matches every condition.
+func (r *Repository[T]) Exists(ctx context.Context, conds ...Cond) (bool, error) {
+	where, args := whereClause(conds)
+
+	// CASE WHEN EXISTS is understood by every dialect, unlike LIMIT or a bare EXISTS.
+	query := "SELECT CASE WHEN EXISTS (SELECT 1 FROM " + r.table + where + ") THEN 1 ELSE 0 END"
+
+	var n int64
+	if err := queryScalar(ctx, r.db, query, &n, args...); err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// keyConds returns the conditions that select the row with the pk values.
+func (r *Repository[T]) keyConds(keys []interface{}) ([]Cond, error) {
+	var zero T
+
+	t := reflect.TypeOf(zero)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rdbx: cannot map rows to %T, want a struct", zero)
+	}
+
+	fields := keyColumns(internal.MappingOf(t))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("rdbx: %s has no pk column", t)
+	}
+
+	if len(keys) != len(fields) {
+		columns := make([]string, len(fields))
+		for i, f := range fields {
+			columns[i] = f.Column
+		}
+
+		return nil, fmt.Errorf("rdbx: %s is identified by %s, got %d values", t, strings.Join(columns, ", "), len(keys))
+	}
+
+	conds := make([]Cond, len(fields))
+	for i, f := range fields {
+		conds[i] = Eq(f.Column, keys[i])
+	}
+
+	return conds, nil
+}
+
+// queryScalar runs a query that returns a single column and scans its last row into dest.
+// The rows are read to the end, so the result can be cached.
+func queryScalar(ctx context.Context, db DBTX, query string, dest interface{}, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(dest); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
@@ -0,0 +1,102 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type user struct {
+		ID     int64  `column:"id,pk,autoincrement"`
+		Name   string `column:"name"`
+		Status string `column:"status"`
+	}
+
+	ctx := context.Background()
+	repo := NewRepository[user](NewDbx(db, newMapCache()), "users")
+
+	mock.ExpectQuery("SELECT id, name, status FROM users WHERE id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status"}).AddRow(1, "alie", "active"))
+
+	for i := 0; i < 2; i++ {
+		u, err := repo.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, user{ID: 1, Name: "alie", Status: "active"}, u)
+	}
+
+	mock.ExpectQuery("SELECT id, name, status FROM users WHERE id = ?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status"}))
+
+	_, err = repo.Get(ctx, 2)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = repo.Get(ctx, 1, 2)
+	assert.Error(t, err)
+
+	mock.ExpectQuery("SELECT id, name, status FROM users WHERE status = ? AND id IN (?, ?) AND name IS NOT NULL").
+		WithArgs("active", int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status"}).AddRow(1, "alie", "active"))
+
+	users, err := repo.List(ctx, Eq("status", "active"), In("id", []int64{1, 3}), Ne("name", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "alie", Status: "active"}}, users)
+
+	mock.ExpectQuery("SELECT COUNT(*) FROM users WHERE status = ?").
+		WithArgs("active").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	for i := 0; i < 2; i++ {
+		n, err := repo.Count(ctx, Eq("status", "active"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	}
+
+	mock.ExpectExec("INSERT INTO users (name, status) VALUES (?, ?)").
+		WithArgs("bob", "active").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+
+	u := user{Name: "bob", Status: "active"}
+	assert.NoError(t, repo.Create(ctx, &u))
+	assert.Equal(t, int64(2), u.ID)
+
+	// The insert invalidated the cached count.
+	mock.ExpectQuery("SELECT COUNT(*) FROM users WHERE status = ?").
+		WithArgs("active").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	n, err := repo.Count(ctx, Eq("status", "active"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	mock.ExpectExec("UPDATE users SET name = ?, status = ? WHERE id = ?").
+		WithArgs("bob", "inactive", int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	u.Status = "inactive"
+	assert.NoError(t, repo.Update(ctx, u))
+
+	mock.ExpectQuery("SELECT CASE WHEN EXISTS (SELECT 1 FROM users WHERE name = ?) THEN 1 ELSE 0 END").
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(1))
+
+	ok, err := repo.Exists(ctx, Eq("name", "bob"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	mock.ExpectExec("DELETE FROM users WHERE id = ?").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Delete(ctx, 2))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
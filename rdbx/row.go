@@ -1,79 +1,187 @@
 package rdbx
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"fmt"
-	"reflect"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"time"
 )
 
+// Rows wraps sql.Rows and records the rows that are read, so a fully consumed
+// result set can be stored in the cache when it is closed.
+// On a cache hit the embedded sql.Rows replays the cached result set instead of reading from the database.
 type Rows struct {
 	*sql.Rows
 
-	cachedRows *bytes.Buffer
+	target *cacheTarget // Where the rows are cached, nil when they are not.
 
-	cache Cache
+	ctx context.Context
 
-	queryKey string
+	cached   bool          // Whether the rows are replayed from the cache.
+	recorded *cachedResult // The rows read so far, nil when the result set will not be cached.
+	size     int           // The estimated encoded size of the recorded rows.
+	done     bool          // Whether Next has reached the end of the result set.
+	start    time.Time     // When the query started, to measure how long the result set took to compute.
 
-	ctx context.Context
+	onClose func(res *cachedResult) // Called once on Close with the complete result set, or nil.
 }
 
-func (r *Rows) Close() error {
-	if err := r.cache.Set(r.ctx, r.queryKey, r.cachedRows.Bytes()); err != nil {
-		return err
+// newRows wraps rows read from the database and prepares them to be recorded for the cache.
+func newRows(ctx context.Context, rows *sql.Rows, target *cacheTarget) *Rows {
+	r := &Rows{
+		ctx:    ctx,
+		Rows:   rows,
+		target: target,
+	}
+
+	if target == nil {
+		return r
+	}
+
+	r.start = target.now()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return r
 	}
 
-	return r.Rows.Close()
+	r.recorded = &cachedResult{columns: columns}
+
+	return r
 }
 
-func (r *Rows) Next() bool {
-	return r.Rows.Next()
+// newCachedRows returns Rows that replay a result set decoded from the cache.
+func newCachedRows(ctx context.Context, res *cachedResult) (*Rows, error) {
+	rows, err := replayRows(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rows{
+		ctx:    ctx,
+		Rows:   rows,
+		cached: true,
+	}, nil
 }
 
-func (r *Rows) Scan(dest ...interface{}) error {
-	destDefault := make([]interface{}, len(dest))
-	copy(destDefault, dest)
+// Cached reports whether the rows are served from the cache.
+func (r *Rows) Cached() bool {
+	return r.cached
+}
+
+// Close closes the rows and, when the whole result set was read without error, stores it in the cache.
+// Failing to store the result set is logged and does not fail Close.
+func (r *Rows) Close() error {
+	complete := r.done && r.Rows.Err() == nil
 
-	if err := r.Rows.Scan(dest...); err != nil {
-		return err
+	err := r.Rows.Close()
+
+	var res *cachedResult
+	if err == nil && complete && r.recorded != nil {
+		r.store()
+		res = r.recorded
 	}
 
-	destVal, err := r.makeDestValue(destDefault)
-	if err != nil {
-		return err
+	r.recorded = nil
+
+	if r.onClose != nil {
+		r.onClose(res)
+		r.onClose = nil
 	}
 
-	d := r.joinBytes(destVal)
-	fmt.Printf("d: %s\n", d)
+	return err
+}
+
+// cacheTagTTLMargin is how much longer than the entry its tag sets live, as the entry is written
+// after it is tagged.
+const cacheTagTTLMargin = time.Minute
+
+// store stores the recorded result set in the cache. Errors are logged,
+// result sets larger than the maximum cached result size are silently skipped, and so are
+// result sets whose tags were invalidated while they were read.
+func (r *Rows) store() {
+	if r.target.invalidated() {
+		return
+	}
+
+	now := r.target.now()
+
+	entry, err := r.target.encoding.encode(r.recorded, now.Sub(r.start), now.Add(r.target.ttl))
+	if errors.Is(err, errCachedResultTooLarge) {
+		return
+	}
 
-	_, err = bytes.NewBuffer(d).WriteTo(r.cachedRows)
 	if err != nil {
-		return err
+		log.Printf("cache encode error: %v", err)
+		return
+	}
+
+	// The key is tagged before it is written, so an entry that cannot be invalidated is never stored.
+	tagTTL := r.target.ttl
+	if tagTTL > 0 {
+		tagTTL += cacheTagTTLMargin
+	}
+
+	for _, tagKey := range r.target.tagKeys {
+		if err := r.target.cache.Tag(r.ctx, tagKey, r.target.key, tagTTL); err != nil {
+			log.Printf("cache tag error: %v", err)
+			return
+		}
+	}
+
+	if err := r.target.cache.Set(r.ctx, r.target.key, entry, r.target.ttl); err != nil {
+		log.Printf("cache set error: %v", err)
+		return
 	}
 
-	return nil
+	// An invalidation between the check above and the write may have missed the entry.
+	if r.target.invalidated() {
+		if err := r.target.cache.Delete(r.ctx, r.target.key); err != nil {
+			log.Printf("cache delete error: %v", err)
+		}
+	}
 }
 
-func (r *Rows) joinBytes(destVal [][]byte) []byte {
-	d := bytes.Join(destVal, []byte(","))
-	d = append(d, byte(';'))
+// Next prepares the next result row for reading with the Scan method.
+func (r *Rows) Next() bool {
+	if !r.Rows.Next() {
+		r.done = true
+		return false
+	}
+
+	if r.recorded != nil {
+		r.record()
+	}
 
-	return d
+	return true
 }
 
-func (r *Rows) makeDestValue(dest []interface{}) ([][]byte, error) {
-	col, err := r.Rows.Columns()
-	if err != nil {
-		return nil, err
+// record keeps the driver values of the current row. If they cannot be read, or the result set
+// grows too large to be cached, the result set is not cached, but the caller can still scan the row.
+func (r *Rows) record() {
+	vs := make([]interface{}, len(r.recorded.columns))
+	vPtrs := make([]interface{}, len(vs))
+	for i := range vs {
+		vPtrs[i] = &vs[i]
+	}
+
+	if err := r.Rows.Scan(vPtrs...); err != nil {
+		r.recorded = nil
+		return
+	}
+
+	values := make([]driver.Value, len(vs))
+	for i, v := range vs {
+		values[i] = v
+		r.size += estimateValueSize(v)
 	}
 
-	destVal := make([][]interface{}, len(col))
-	for i := range col {
-		reflectDestVal := reflect.ValueOf(destVal[i])
-		reflectDestVal.Elem().Set(reflect.ValueOf(dest[i]))
+	if !r.target.encoding.fits(r.size) {
+		r.recorded = nil
+		return
 	}
 
-	return [][]byte{}, nil
+	r.recorded.rows = append(r.recorded.rows, values)
 }
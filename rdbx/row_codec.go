@@ -0,0 +1,375 @@
+package rdbx
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// rowCodecVersion is written at the start of every encoded result set so that
+// payloads written by an incompatible version are treated as a cache miss.
+const rowCodecVersion byte = 1
+
+// Value tags of the encoded result set.
+const (
+	tagNull byte = iota
+	tagInt64
+	tagUint64
+	tagFloat64
+	tagBool
+	tagBytes
+	tagString
+	tagTime
+)
+
+// cacheEntryVersion is written at the start of every cache entry.
+const cacheEntryVersion byte = 2
+
+// errRowCodecVersion is returned when a payload was written by another codec version.
+var errRowCodecVersion = errors.New("rdbx: unsupported cached rows version")
+
+// errCacheEntryVersion is returned when a cache entry was written by another version.
+var errCacheEntryVersion = errors.New("rdbx: unsupported cache entry version")
+
+// cacheEntry is what is stored in the cache for a result set: the encoded rows, how long
+// they took to compute and when they expire, which early refresh needs.
+type cacheEntry struct {
+	payload     []byte
+	compression Compression // How the payload is compressed in the encoded entry.
+	delta       time.Duration
+	expiresAt   time.Time
+}
+
+// encodeEntry encodes a cache entry, compressing its payload.
+func encodeEntry(e cacheEntry) []byte {
+	payload := e.compression.compress(e.payload)
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(payload)+2*binary.MaxVarintLen64+2))
+	buf.WriteByte(cacheEntryVersion)
+	buf.WriteByte(byte(e.compression))
+	writeVarint(buf, int64(e.delta))
+	writeVarint(buf, e.expiresAt.UnixNano())
+	buf.Write(payload)
+
+	return buf.Bytes()
+}
+
+// decodeEntry decodes a cache entry produced by encodeEntry, decompressing its payload.
+func decodeEntry(b []byte) (cacheEntry, error) {
+	r := bytes.NewReader(b)
+
+	version, err := r.ReadByte()
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	if version != cacheEntryVersion {
+		return cacheEntry{}, errCacheEntryVersion
+	}
+
+	compression, err := r.ReadByte()
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	delta, err := binary.ReadVarint(r)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	expiresAt, err := binary.ReadVarint(r)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	payload, err := Compression(compression).decompress(b[len(b)-r.Len():])
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	return cacheEntry{
+		payload:     payload,
+		compression: Compression(compression),
+		delta:       time.Duration(delta),
+		expiresAt:   time.Unix(0, expiresAt),
+	}, nil
+}
+
+// cachedResult is a result set that can be stored in and replayed from the cache.
+type cachedResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// RowCodec encodes the result sets stored in the cache. Values are nil, int64, uint64, float64,
+// bool, []byte, string or time.Time, and must decode to the same type so that scanning a cached
+// result set behaves like scanning the database rows.
+type RowCodec interface {
+	EncodeRows(columns []string, rows [][]driver.Value) ([]byte, error)
+	DecodeRows(payload []byte) (columns []string, rows [][]driver.Value, err error)
+}
+
+// BinaryRowCodec is a compact binary RowCodec, it is the default codec of dbx.
+var BinaryRowCodec RowCodec = binaryRowCodec{}
+
+type binaryRowCodec struct{}
+
+func (binaryRowCodec) EncodeRows(columns []string, rows [][]driver.Value) ([]byte, error) {
+	return encodeRows(&cachedResult{columns: columns, rows: rows})
+}
+
+func (binaryRowCodec) DecodeRows(payload []byte) ([]string, [][]driver.Value, error) {
+	res, err := decodeRows(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res.columns, res.rows, nil
+}
+
+// encodeRows encodes a result set into a payload that keeps the driver type of every value.
+func encodeRows(res *cachedResult) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte(rowCodecVersion)
+
+	writeUvarint(buf, uint64(len(res.columns)))
+	for _, c := range res.columns {
+		writeBytes(buf, []byte(c))
+	}
+
+	writeUvarint(buf, uint64(len(res.rows)))
+	for _, row := range res.rows {
+		if len(row) != len(res.columns) {
+			return nil, fmt.Errorf("rdbx: cached row has %d values, want %d", len(row), len(res.columns))
+		}
+
+		for i, v := range row {
+			if err := encodeValue(buf, v); err != nil {
+				return nil, fmt.Errorf("rdbx: column %q: %w", res.columns[i], err)
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeValue writes a single tagged value.
+func encodeValue(buf *bytes.Buffer, v driver.Value) error {
+	var scratch [8]byte
+
+	switch t := v.(type) {
+	case nil:
+		buf.WriteByte(tagNull)
+	case int64:
+		buf.WriteByte(tagInt64)
+		writeVarint(buf, t)
+	case uint64:
+		buf.WriteByte(tagUint64)
+		writeUvarint(buf, t)
+	case float64:
+		buf.WriteByte(tagFloat64)
+		binary.BigEndian.PutUint64(scratch[:], math.Float64bits(t))
+		buf.Write(scratch[:])
+	case bool:
+		buf.WriteByte(tagBool)
+		if t {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case []byte:
+		buf.WriteByte(tagBytes)
+		writeBytes(buf, t)
+	case string:
+		buf.WriteByte(tagString)
+		writeBytes(buf, []byte(t))
+	case time.Time:
+		b, err := t.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte(tagTime)
+		writeBytes(buf, b)
+		writeBytes(buf, []byte(t.Location().String()))
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+
+	return nil
+}
+
+// decodeRows decodes a payload produced by encodeRows.
+func decodeRows(payload []byte) (*cachedResult, error) {
+	r := bytes.NewReader(payload)
+
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	if version != rowCodecVersion {
+		return nil, errRowCodecVersion
+	}
+
+	colCount, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if colCount > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	res := &cachedResult{columns: make([]string, colCount)}
+	for i := range res.columns {
+		b, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+
+		res.columns[i] = string(b)
+	}
+
+	rowCount, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every value takes a byte at least. Rows without columns take none, but bounding them too
+	// keeps a corrupted count from allocating without limit; such a result set is then a cache miss.
+	if rowCount > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	res.rows = make([][]driver.Value, 0, rowCount)
+	for i := uint64(0); i < rowCount; i++ {
+		row := make([]driver.Value, colCount)
+		for j := range row {
+			if row[j], err = decodeValue(r); err != nil {
+				return nil, err
+			}
+		}
+
+		res.rows = append(res.rows, row)
+	}
+
+	return res, nil
+}
+
+// decodeValue reads a single tagged value.
+func decodeValue(r *bytes.Reader) (driver.Value, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case tagNull:
+		return nil, nil
+	case tagInt64:
+		return binary.ReadVarint(r)
+	case tagUint64:
+		return binary.ReadUvarint(r)
+	case tagFloat64:
+		var scratch [8]byte
+		if _, err := io.ReadFull(r, scratch[:]); err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(binary.BigEndian.Uint64(scratch[:])), nil
+	case tagBool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		return b == 1, nil
+	case tagBytes:
+		return readBytes(r)
+	case tagString:
+		b, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	case tagTime:
+		b, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var t time.Time
+		if err := t.UnmarshalBinary(b); err != nil {
+			return nil, err
+		}
+
+		name, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return restoreLocation(t, string(name)), nil
+	default:
+		return nil, fmt.Errorf("rdbx: unknown cached value tag %d", tag)
+	}
+}
+
+// restoreLocation puts t back in its named location, the binary encoding of
+// time.Time only keeps the zone offset.
+func restoreLocation(t time.Time, name string) time.Time {
+	switch name {
+	case "", "UTC":
+		return t
+	case "Local":
+		return t.In(time.Local)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return t
+	}
+
+	return t.In(loc)
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], v)
+	buf.Write(scratch[:n])
+}
+
+func writeVarint(buf *bytes.Buffer, v int64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(scratch[:], v)
+	buf.Write(scratch[:n])
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	writeUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
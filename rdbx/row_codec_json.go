@@ -0,0 +1,171 @@
+package rdbx
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JSONRowCodec is a RowCodec that stores result sets as JSON, which is larger and slower
+// than BinaryRowCodec but easier to inspect, e.g.
+//
+//	{"columns":["id","name"],"rows":[[{"int64":1},{"string":"alie"}]]}
+//
+// As with every codec, the stored value starts with a binary header holding the entry version,
+// the compression, and the compute time and expiry as varints, and the JSON that follows is
+// compressed when WithCompression applies. It is only plain JSON past the header of an
+// uncompressed entry.
+var JSONRowCodec RowCodec = jsonRowCodec{}
+
+// errJSONRowValue is returned when a JSON value does not hold exactly one typed value.
+var errJSONRowValue = errors.New("rdbx: cached JSON value must hold exactly one type")
+
+type jsonRowCodec struct{}
+
+// jsonRows is the JSON document of a result set.
+type jsonRows struct {
+	Columns []string       `json:"columns"`
+	Rows    [][]*jsonValue `json:"rows"`
+}
+
+// jsonValue is a driver value keyed by its type, so it decodes to the same type.
+// A NULL is a nil *jsonValue, encoded as null.
+type jsonValue struct {
+	Int64    *int64     `json:"int64,omitempty"`
+	Uint64   *uint64    `json:"uint64,omitempty"`
+	Float64  *float64   `json:"float64,omitempty"`
+	Bool     *bool      `json:"bool,omitempty"`
+	Bytes    *[]byte    `json:"bytes,omitempty"`
+	String   *string    `json:"string,omitempty"`
+	Time     *time.Time `json:"time,omitempty"`
+	Location string     `json:"location,omitempty"` // The location name of Time.
+}
+
+func (jsonRowCodec) EncodeRows(columns []string, rows [][]driver.Value) ([]byte, error) {
+	doc := jsonRows{Columns: columns, Rows: make([][]*jsonValue, len(rows))}
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("rdbx: cached row has %d values, want %d", len(row), len(columns))
+		}
+
+		doc.Rows[i] = make([]*jsonValue, len(row))
+		for j, v := range row {
+			jv, err := newJSONValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("rdbx: column %q: %w", columns[j], err)
+			}
+
+			doc.Rows[i][j] = jv
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func (jsonRowCodec) DecodeRows(payload []byte) ([]string, [][]driver.Value, error) {
+	var doc jsonRows
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([][]driver.Value, len(doc.Rows))
+	for i, row := range doc.Rows {
+		if len(row) != len(doc.Columns) {
+			return nil, nil, fmt.Errorf("rdbx: cached row has %d values, want %d", len(row), len(doc.Columns))
+		}
+
+		rows[i] = make([]driver.Value, len(row))
+		for j, jv := range row {
+			v, err := jv.value()
+			if err != nil {
+				return nil, nil, fmt.Errorf("rdbx: column %q: %w", doc.Columns[j], err)
+			}
+
+			rows[i][j] = v
+		}
+	}
+
+	return doc.Columns, rows, nil
+}
+
+// newJSONValue returns the JSON value of a driver value, nil for a NULL.
+func newJSONValue(v driver.Value) (*jsonValue, error) {
+	jv := &jsonValue{}
+
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		jv.Int64 = &t
+	case uint64:
+		jv.Uint64 = &t
+	case float64:
+		jv.Float64 = &t
+	case bool:
+		jv.Bool = &t
+	case []byte:
+		if t == nil {
+			t = []byte{}
+		}
+
+		jv.Bytes = &t
+	case string:
+		jv.String = &t
+	case time.Time:
+		jv.Time = &t
+		jv.Location = t.Location().String()
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+
+	return jv, nil
+}
+
+// value returns the driver value held by a decoded JSON value, nil when it is null.
+func (jv *jsonValue) value() (driver.Value, error) {
+	if jv == nil {
+		return nil, nil
+	}
+
+	var (
+		v driver.Value
+		n int
+	)
+
+	if jv.Int64 != nil {
+		v, n = *jv.Int64, n+1
+	}
+
+	if jv.Uint64 != nil {
+		v, n = *jv.Uint64, n+1
+	}
+
+	if jv.Float64 != nil {
+		v, n = *jv.Float64, n+1
+	}
+
+	if jv.Bool != nil {
+		v, n = *jv.Bool, n+1
+	}
+
+	if jv.Bytes != nil {
+		v, n = *jv.Bytes, n+1
+	}
+
+	if jv.String != nil {
+		v, n = *jv.String, n+1
+	}
+
+	if jv.Time != nil {
+		v, n = restoreLocation(*jv.Time, jv.Location), n+1
+	}
+
+	if n != 1 {
+		return nil, errJSONRowValue
+	}
+
+	return v, nil
+}
@@ -0,0 +1,110 @@
+package rdbx
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rowCodec(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		jakarta = time.FixedZone("Asia/Jakarta", 7*3600)
+	}
+
+	createdAt := time.Date(2023, 5, 1, 10, 30, 0, 123456789, jakarta)
+
+	res := &cachedResult{
+		columns: []string{"id", "big", "score", "active", "avatar", "empty", "name", "created_at", "deleted_at"},
+		rows: [][]driver.Value{
+			{int64(-42), uint64(1 << 63), 3.14159, true, []byte{0, 1, 255}, []byte{}, "alie", createdAt, nil},
+			{int64(0), uint64(0), -0.5, false, nil, []byte{}, "", createdAt.UTC(), nil},
+		},
+	}
+
+	for name, codec := range map[string]RowCodec{"binary": BinaryRowCodec, "json": JSONRowCodec} {
+		t.Run(name, func(t *testing.T) {
+			payload, err := codec.EncodeRows(res.columns, res.rows)
+			assert.NoError(t, err)
+
+			columns, rows, err := codec.DecodeRows(payload)
+			assert.NoError(t, err)
+			assert.Equal(t, res.columns, columns)
+			assert.Len(t, rows, len(res.rows))
+
+			for i, row := range res.rows {
+				for j, want := range row {
+					switch w := want.(type) {
+					case time.Time:
+						gt, ok := rows[i][j].(time.Time)
+						assert.True(t, ok)
+						assert.True(t, w.Equal(gt))
+						assert.Equal(t, w.Location().String(), gt.Location().String())
+					default:
+						assert.Equal(t, want, rows[i][j], "row %d column %s", i, res.columns[j])
+					}
+				}
+			}
+
+			_, err = codec.EncodeRows([]string{"a"}, [][]driver.Value{{int32(1)}})
+			assert.Error(t, err)
+
+			_, _, err = codec.DecodeRows(payload[:len(payload)-3])
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("binary version", func(t *testing.T) {
+		_, err := decodeRows([]byte{rowCodecVersion + 1})
+		assert.ErrorIs(t, err, errRowCodecVersion)
+	})
+
+	t.Run("binary row count beyond the payload", func(t *testing.T) {
+		for _, payload := range [][]byte{
+			{rowCodecVersion, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+			{rowCodecVersion, 1, 1, 'a', 0xff, 0xff, 0xff, 0xff, 0x0f},
+		} {
+			_, err := decodeRows(payload)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("json value with several types", func(t *testing.T) {
+		_, _, err := JSONRowCodec.DecodeRows([]byte(`{"columns":["a"],"rows":[[{"int64":1,"string":"1"}]]}`))
+		assert.ErrorIs(t, err, errJSONRowValue)
+	})
+}
+
+func Test_cacheEntry(t *testing.T) {
+	payload := bytes.Repeat([]byte("alie,rossner,worker;"), 100)
+
+	for _, compression := range []Compression{NoCompression, GzipCompression, SnappyCompression} {
+		e := cacheEntry{
+			payload:     payload,
+			compression: compression,
+			delta:       150 * time.Millisecond,
+			expiresAt:   time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		b := encodeEntry(e)
+		if compression != NoCompression {
+			assert.Less(t, len(b), len(payload), "compression %d", compression)
+		}
+
+		got, err := decodeEntry(b)
+		assert.NoError(t, err)
+		assert.Equal(t, e.payload, got.payload)
+		assert.Equal(t, e.compression, got.compression)
+		assert.Equal(t, e.delta, got.delta)
+		assert.True(t, e.expiresAt.Equal(got.expiresAt))
+	}
+
+	_, err := decodeEntry([]byte{cacheEntryVersion + 1})
+	assert.ErrorIs(t, err, errCacheEntryVersion)
+
+	_, err = decodeEntry([]byte{cacheEntryVersion, 9, 0, 0})
+	assert.Error(t, err)
+}
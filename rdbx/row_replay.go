@@ -0,0 +1,104 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+)
+
+// replayDB serves cached result sets through database/sql, so scanning a cache
+// hit goes through exactly the same conversions as scanning a database read.
+var (
+	replayDB     *sql.DB
+	replayDBOnce sync.Once
+)
+
+// errReplayUnsupported is returned for any operation other than replaying rows.
+var errReplayUnsupported = errors.New("rdbx: operation not supported on cached rows")
+
+// replayRows returns sql.Rows that iterate over a cached result set.
+func replayRows(ctx context.Context, res *cachedResult) (*sql.Rows, error) {
+	replayDBOnce.Do(func() {
+		replayDB = sql.OpenDB(replayConnector{})
+	})
+
+	return replayDB.QueryContext(ctx, "", res)
+}
+
+// replayConnector opens connections that replay cached result sets.
+type replayConnector struct{}
+
+func (replayConnector) Connect(context.Context) (driver.Conn, error) {
+	return replayConn{}, nil
+}
+
+func (replayConnector) Driver() driver.Driver {
+	return replayDriver{}
+}
+
+type replayDriver struct{}
+
+func (replayDriver) Open(string) (driver.Conn, error) {
+	return replayConn{}, nil
+}
+
+// replayConn is a stateless connection whose only query argument is the cachedResult to replay.
+type replayConn struct{}
+
+func (replayConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errReplayUnsupported
+}
+
+func (replayConn) Close() error {
+	return nil
+}
+
+func (replayConn) Begin() (driver.Tx, error) {
+	return nil, errReplayUnsupported
+}
+
+// CheckNamedValue lets the cachedResult through database/sql argument conversion untouched.
+func (replayConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (replayConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, errReplayUnsupported
+	}
+
+	res, ok := args[0].Value.(*cachedResult)
+	if !ok {
+		return nil, errReplayUnsupported
+	}
+
+	return &replayResultRows{res: res}, nil
+}
+
+// replayResultRows implements driver.Rows on top of a cachedResult.
+type replayResultRows struct {
+	res *cachedResult
+	pos int
+}
+
+func (r *replayResultRows) Columns() []string {
+	return r.res.columns
+}
+
+func (r *replayResultRows) Close() error {
+	return nil
+}
+
+func (r *replayResultRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.res.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.res.rows[r.pos])
+	r.pos++
+
+	return nil
+}
@@ -0,0 +1,333 @@
+package rdbx
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// scannerType is the type of sql.Scanner.
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// scanOptions configures how rows are scanned into structs.
+type scanOptions struct {
+	zeroOnNull bool // Whether a NULL sets any field to its zero value.
+}
+
+// scanRows scans the rows into dest: a struct, a map with string keys, a scalar, or a slice of
+// any of them. Rows are appended to a slice, other values receive the last row. Columns without
+// a field are skipped, and scalars need a query of a single column.
+func scanRows(rows *Rows, dest reflect.Value, opts scanOptions) error {
+	isSlice := dest.Kind() == reflect.Slice && !isScalar(dest.Type())
+
+	elType := dest.Type()
+	if isSlice {
+		elType = elType.Elem()
+	}
+
+	sc, err := newRowScanner(rows, elType, opts)
+	if err != nil {
+		return err
+	}
+
+	el := dest
+
+	for rows.Next() {
+		if isSlice {
+			// A fresh element per row, so rows do not share the structs allocated behind pointers.
+			el = reflect.New(elType).Elem()
+		}
+
+		if err := sc.scan(rows, el); err != nil {
+			return err
+		}
+
+		if isSlice {
+			dest.Set(reflect.Append(dest, el))
+		}
+	}
+
+	return rows.Err()
+}
+
+// rowScanner scans the current row of a result set into a value.
+type rowScanner interface {
+	scan(rows *Rows, el reflect.Value) error
+}
+
+// newRowScanner returns the scanner of the rows into values of the type.
+func newRowScanner(rows *Rows, elType reflect.Type, opts scanOptions) (rowScanner, error) {
+	switch {
+	case isScalar(elType):
+		return newScalarScanner(rows, elType, opts)
+	case elType.Kind() == reflect.Map && elType.Key().Kind() == reflect.String:
+		return newMapScanner(rows, opts)
+	case elType.Kind() == reflect.Struct:
+		return newStructScanner(rows, elType, opts)
+	default:
+		return nil, fmt.Errorf("rdbx: cannot scan rows into %s", elType)
+	}
+}
+
+// isScalar reports whether values of the type receive a single column: basic types, []byte,
+// time.Time, sql.Scanner implementations, interfaces, and pointers to any of them.
+func isScalar(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(scannerType) || t == timeType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return isScalar(t.Elem())
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	case reflect.Struct, reflect.Map, reflect.Array, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return false
+	default:
+		return true
+	}
+}
+
+// scalarScanner scans the single column of the rows into scalars.
+type scalarScanner struct {
+	s *valueScanner
+}
+
+// newScalarScanner checks that the rows have a single column.
+func newScalarScanner(rows *Rows, elType reflect.Type, opts scanOptions) (*scalarScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(columns) != 1 {
+		return nil, fmt.Errorf("rdbx: cannot scan %d columns into %s, want a single column", len(columns), elType)
+	}
+
+	return &scalarScanner{s: &valueScanner{column: columns[0], zeroOnNull: opts.zeroOnNull}}, nil
+}
+
+// scan scans the current row into el.
+func (sc *scalarScanner) scan(rows *Rows, el reflect.Value) error {
+	sc.s.dst = el
+
+	if err := rows.Scan(sc.s); err != nil {
+		if sc.s.err != nil {
+			return sc.s.err
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// mapScanner scans rows into maps keyed by column.
+type mapScanner struct {
+	scanners []*valueScanner
+	dests    []interface{}
+}
+
+// newMapScanner reads the columns of the rows.
+func newMapScanner(rows *Rows, opts scanOptions) (*mapScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &mapScanner{
+		scanners: make([]*valueScanner, len(columns)),
+		dests:    make([]interface{}, len(columns)),
+	}
+
+	for i, col := range columns {
+		sc.scanners[i] = &valueScanner{column: col, zeroOnNull: opts.zeroOnNull}
+		sc.dests[i] = sc.scanners[i]
+	}
+
+	return sc, nil
+}
+
+// scan scans the current row into el, a map that is allocated when nil.
+func (sc *mapScanner) scan(rows *Rows, el reflect.Value) error {
+	if el.IsNil() {
+		el.Set(reflect.MakeMapWithSize(el.Type(), len(sc.scanners)))
+	}
+
+	for _, s := range sc.scanners {
+		s.dst = reflect.New(el.Type().Elem()).Elem()
+	}
+
+	if err := rows.Scan(sc.dests...); err != nil {
+		for _, s := range sc.scanners {
+			if s.err != nil {
+				return s.err
+			}
+		}
+
+		return err
+	}
+
+	for _, s := range sc.scanners {
+		el.SetMapIndex(reflect.ValueOf(s.column).Convert(el.Type().Key()), s.dst)
+	}
+
+	return nil
+}
+
+// valueScanner is a sql.Scanner that assigns a column value to a scalar or a map value.
+type valueScanner struct {
+	column     string
+	zeroOnNull bool
+
+	dst reflect.Value // The destination of the current row.
+	err error         // The descriptive error of a failed Scan.
+}
+
+// Scan assigns the column value to the destination.
+func (s *valueScanner) Scan(src interface{}) error {
+	if err := assignValue(s.dst, src, s.zeroOnNull); err != nil {
+		s.err = fmt.Errorf("rdbx: column %q into %s: %w", s.column, s.dst.Type(), err)
+
+		return s.err
+	}
+
+	return nil
+}
+
+// structScanner scans the rows of a result set into structs of one type.
+type structScanner struct {
+	scanners []*fieldScanner
+	dests    []interface{} // The scan destination of every column.
+}
+
+// newStructScanner maps the columns of the rows to the fields of the struct type.
+func newStructScanner(rows *Rows, elType reflect.Type, opts scanOptions) (*structScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := internal.MappingOf(elType)
+
+	sc := &structScanner{
+		scanners: make([]*fieldScanner, 0, len(columns)),
+		dests:    make([]interface{}, len(columns)),
+	}
+
+	for i, col := range columns {
+		f, ok := mapping.Field(col)
+		if !ok {
+			sc.dests[i] = discardScanner{}
+			continue
+		}
+
+		s := &fieldScanner{
+			column:     col,
+			structName: elType.Name(),
+			field:      f,
+			zeroOnNull: opts.zeroOnNull || f.Options.Has("zeronull"),
+		}
+
+		sc.scanners = append(sc.scanners, s)
+		sc.dests[i] = s
+	}
+
+	return sc, nil
+}
+
+// scan scans the current row into el, an addressable struct.
+func (sc *structScanner) scan(rows *Rows, el reflect.Value) error {
+	for _, s := range sc.scanners {
+		s.dst = internal.FieldByIndex(el, s.field.Index)
+	}
+
+	if err := rows.Scan(sc.dests...); err != nil {
+		for _, s := range sc.scanners {
+			if s.err != nil {
+				return s.err
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// fieldScanner is a sql.Scanner that assigns a column value to the field of a struct.
+type fieldScanner struct {
+	column     string
+	structName string
+	field      *internal.Field
+	zeroOnNull bool
+
+	dst reflect.Value // The field of the current row.
+	err error         // The descriptive error of a failed Scan.
+}
+
+// Scan assigns the column value to the field.
+func (s *fieldScanner) Scan(src interface{}) error {
+	if !s.dst.IsValid() || !s.dst.CanSet() {
+		return nil
+	}
+
+	if err := assignValue(s.dst, src, s.zeroOnNull); err != nil {
+		s.err = fmt.Errorf("rdbx: column %q into field %s.%s (%s): %w",
+			s.column, s.structName, s.field.Name, s.dst.Type(), err)
+
+		return s.err
+	}
+
+	return nil
+}
+
+// discardScanner is a sql.Scanner that ignores the values of columns without a field.
+type discardScanner struct{}
+
+func (discardScanner) Scan(interface{}) error {
+	return nil
+}
+
+// assignValue assigns a column value to a field.
+// A NULL sets pointer and interface fields to nil, and other fields to their zero value when zeroOnNull is set.
+func assignValue(dst reflect.Value, src interface{}, zeroOnNull bool) error {
+	if scanner, ok := dst.Addr().Interface().(sql.Scanner); ok {
+		// Scanners have always received text columns as a string.
+		if b, ok := src.([]byte); ok {
+			src = string(b)
+		}
+
+		return scanner.Scan(src)
+	}
+
+	if src == nil && (dst.Kind() == reflect.Ptr || dst.Kind() == reflect.Interface) {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if dst.Kind() == reflect.Ptr {
+		v := reflect.New(dst.Type().Elem())
+		if err := assignValue(v.Elem(), src, zeroOnNull); err != nil {
+			return err
+		}
+
+		dst.Set(v)
+
+		return nil
+	}
+
+	if src == nil {
+		if !zeroOnNull {
+			return errNullValue
+		}
+
+		dst.Set(reflect.Zero(dst.Type()))
+
+		return nil
+	}
+
+	return convertAssign(dst, src)
+}
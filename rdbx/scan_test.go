@@ -0,0 +1,59 @@
+package rdbx
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_scanRows(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	type model struct {
+		ID     int64       `column:"id"`
+		Avatar []byte      `column:"avatar"`
+		Extra  interface{} `column:"extra"`
+	}
+
+	query := "SELECT id, avatar, extra, unknown FROM users"
+
+	queryRows := func(t *testing.T) *Rows {
+		mock.ExpectQuery(query).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "avatar", "extra", "unknown"}).
+				AddRow(int64(1), []byte{1, 2}, []byte("a"), "x").
+				AddRow(int64(2), []byte{3}, int64(7), "y"),
+		)
+
+		rows, err := NewDbx(db, nil).QueryContext(context.Background(), query)
+		assert.NoError(t, err)
+
+		return rows
+	}
+
+	t.Run("slice", func(t *testing.T) {
+		rows := queryRows(t)
+		defer rows.Close()
+
+		var got []model
+		assert.NoError(t, scanRows(rows, reflect.ValueOf(&got).Elem(), scanOptions{}))
+		assert.Equal(t, []model{
+			{ID: 1, Avatar: []byte{1, 2}, Extra: "a"},
+			{ID: 2, Avatar: []byte{3}, Extra: int64(7)},
+		}, got)
+	})
+
+	t.Run("struct receives the last row", func(t *testing.T) {
+		rows := queryRows(t)
+		defer rows.Close()
+
+		var got model
+		assert.NoError(t, scanRows(rows, reflect.ValueOf(&got).Elem(), scanOptions{}))
+		assert.Equal(t, model{ID: 2, Avatar: []byte{3}, Extra: int64(7)}, got)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
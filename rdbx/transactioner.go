@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"sync"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/go-redsync/redsync/v4"
@@ -32,6 +33,7 @@ func (t *tx) EnableTx(ctx context.Context) *enabledTx {
 	tx, err := t.dbtx.Begin()
 
 	ctx = context.WithValue(ctx, &contextKeyEnableSqlTx{}, tx)
+	ctx = context.WithValue(ctx, contextKeyTxHooks{}, &txHooks{})
 
 	return &enabledTx{
 		ctx:         ctx,
@@ -42,6 +44,35 @@ func (t *tx) EnableTx(ctx context.Context) *enabledTx {
 	}
 }
 
+// contextKeyTxHooks is a context key used to register functions that run once the transaction is committed.
+type contextKeyTxHooks struct{}
+
+// txHooks holds the functions to run once a transaction has been committed, such as cache invalidations.
+type txHooks struct {
+	mu sync.Mutex
+	fn []func(ctx context.Context)
+}
+
+// afterCommit registers a function to run once the transaction has been committed.
+func (h *txHooks) afterCommit(fn func(ctx context.Context)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.fn = append(h.fn, fn)
+}
+
+// runAfterCommit runs the registered functions in registration order.
+func (h *txHooks) runAfterCommit(ctx context.Context) {
+	h.mu.Lock()
+	fns := h.fn
+	h.fn = nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(ctx)
+	}
+}
+
 // enabledTx represents an enabled transaction with a Redis database.
 type enabledTx struct {
 	ctx         context.Context       // The context of the transaction.
@@ -85,6 +116,11 @@ func (t enabledTx) Exec(actionFn func(ctx context.Context) error) error {
 			default:
 				if t.err = tx.Commit(); t.err != nil {
 					log.Printf("[Transactioner Error Commit] %v", t.err)
+					break
+				}
+
+				if hooks, ok := t.ctx.Value(contextKeyTxHooks{}).(*txHooks); ok {
+					hooks.runAfterCommit(t.ctx)
 				}
 			}
 		}
@@ -37,7 +37,7 @@ func (t *txSuite) SetupTest() {
 	t.rdmock = rdmock
 
 	t.rClient = rdclient
-	t.dbtx = NewDbx(db, &cache{redis: rdclient})
+	t.dbtx = NewDbx(db, NewRedisCache(rdclient))
 }
 
 func (t *txSuite) Test_tx_Lock() {
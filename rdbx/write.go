@@ -0,0 +1,377 @@
+package rdbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/farislr/commoneer/rdbx/internal"
+)
+
+// Tabler is implemented by the models that declare their table, which InsertStruct, InsertMany,
+// UpdateStruct and Upsert write to.
+//
+// The column tag options of the model control which columns are written:
+//
+//	pk             a key column, that UpdateStruct updates by and Upsert conflicts on
+//	autoincrement  a column set by the database, never written
+//	readonly       a column never written, such as one with a database default
+//	omitempty      a column not written when the field holds its zero value
+type Tabler interface {
+	TableName() string
+}
+
+// writeModel returns the table and the struct value of a model, a struct or a pointer to
+// a struct that implements Tabler.
+func writeModel(model interface{}) (string, reflect.Value, error) {
+	v, err := structValue(model)
+	if err != nil {
+		return "", reflect.Value{}, err
+	}
+
+	t, ok := model.(Tabler)
+	if !ok && v.CanAddr() {
+		t, ok = v.Addr().Interface().(Tabler)
+	}
+
+	if !ok {
+		return "", reflect.Value{}, fmt.Errorf("rdbx: cannot write %T, it does not implement Tabler", model)
+	}
+
+	return t.TableName(), v, nil
+}
+
+// structValue returns the struct value of a model, a struct or a pointer to a struct.
+func structValue(model interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(model)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("rdbx: cannot write %T, want a struct", model)
+	}
+
+	return v, nil
+}
+
+// writeValue returns the value of the field to write, nil when the field is behind a nil
+// embedded pointer, and whether the value is empty for the omitempty option.
+func writeValue(v reflect.Value, f *internal.Field) (interface{}, bool) {
+	fv, ok := fieldValue(v, f.Index)
+	if !ok {
+		return nil, true
+	}
+
+	return fv.Interface(), fv.IsZero()
+}
+
+// isInsertable reports whether the field is written by an INSERT.
+func isInsertable(f *internal.Field) bool {
+	return !f.Options.Has("readonly") && !f.Options.Has("autoincrement")
+}
+
+// insertColumns returns the columns of the model an INSERT writes, and their values.
+func insertColumns(v reflect.Value, mapping *internal.Mapping) ([]string, []interface{}) {
+	var columns []string
+	var args []interface{}
+
+	for i := range mapping.Fields {
+		f := &mapping.Fields[i]
+		if !isInsertable(f) {
+			continue
+		}
+
+		value, empty := writeValue(v, f)
+		if empty && f.Options.Has("omitempty") {
+			continue
+		}
+
+		columns = append(columns, f.Column)
+		args = append(args, value)
+	}
+
+	return columns, args
+}
+
+// keyColumns returns the pk columns of the mapping.
+func keyColumns(mapping *internal.Mapping) []*internal.Field {
+	var keys []*internal.Field
+	for i := range mapping.Fields {
+		if mapping.Fields[i].Options.Has("pk") {
+			keys = append(keys, &mapping.Fields[i])
+		}
+	}
+
+	return keys
+}
+
+// insertQuery returns an INSERT of rows rows of the columns.
+func insertQuery(table string, columns []string, rows int) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+
+	row := "(" + strings.Repeat("?, ", len(columns)-1) + "?)"
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(row)
+	}
+
+	return b.String()
+}
+
+// InsertStruct inserts the model, a struct or a pointer to a struct that implements Tabler, into
+// its table. With MySQL and SQLite, the zero autoincrement field of a pointer model is set to the
+// id of the inserted row.
+func InsertStruct(ctx context.Context, db DBTX, model interface{}) (sql.Result, error) {
+	table, v, err := writeModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	return insertStruct(ctx, db, table, v)
+}
+
+// insertStruct inserts the struct value into the table.
+func insertStruct(ctx context.Context, db DBTX, table string, v reflect.Value) (sql.Result, error) {
+	mapping := internal.MappingOf(v.Type())
+
+	columns, args := insertColumns(v, mapping)
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("rdbx: %s has no column to insert", v.Type())
+	}
+
+	res, err := db.ExecContext(ctx, insertQuery(table, columns, 1), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if dialectOf(db).hasLastInsertID() && v.CanSet() {
+		if err := setInsertID(v, mapping, res); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// setInsertID sets the zero integer autoincrement field of the model to the id of the inserted row.
+func setInsertID(v reflect.Value, mapping *internal.Mapping, res sql.Result) error {
+	for i := range mapping.Fields {
+		f := &mapping.Fields[i]
+		if !f.Options.Has("autoincrement") {
+			continue
+		}
+
+		fv := internal.FieldByIndex(v, f.Index)
+		if !fv.IsValid() || !fv.CanSet() || !fv.IsZero() {
+			return nil
+		}
+
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return convertAssign(fv, id)
+	}
+
+	return nil
+}
+
+// InsertMany inserts the models, structs or pointers to structs that implement Tabler, with
+// multi-row INSERT statements, as many rows per statement as the placeholder limit of the dialect
+// allows. It returns the number of inserted rows. An omitempty column is left out only when it is
+// empty in every model. Run it in a transaction for the statements to be atomic.
+func InsertMany[T any](ctx context.Context, db DBTX, models []T) (int64, error) {
+	if len(models) == 0 {
+		return 0, nil
+	}
+
+	table, first, err := writeModel(models[0])
+	if err != nil {
+		return 0, err
+	}
+
+	mapping := internal.MappingOf(first.Type())
+
+	values := make([]reflect.Value, len(models))
+	for i := range models {
+		if _, values[i], err = writeModel(models[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	var fields []*internal.Field
+	for i := range mapping.Fields {
+		f := &mapping.Fields[i]
+		if !isInsertable(f) {
+			continue
+		}
+
+		if f.Options.Has("omitempty") && allEmpty(values, f) {
+			continue
+		}
+
+		fields = append(fields, f)
+	}
+
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("rdbx: %T has no column to insert", models[0])
+	}
+
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.Column
+	}
+
+	chunk := dialectOf(db).maxArgs() / len(columns)
+	if chunk == 0 {
+		chunk = 1
+	}
+
+	var inserted int64
+
+	for start := 0; start < len(values); start += chunk {
+		end := start + chunk
+		if end > len(values) {
+			end = len(values)
+		}
+
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, v := range values[start:end] {
+			for _, f := range fields {
+				value, _ := writeValue(v, f)
+				args = append(args, value)
+			}
+		}
+
+		res, err := db.ExecContext(ctx, insertQuery(table, columns, end-start), args...)
+		if err != nil {
+			return inserted, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return inserted, err
+		}
+
+		inserted += n
+	}
+
+	return inserted, nil
+}
+
+// allEmpty reports whether the field is empty in every model.
+func allEmpty(values []reflect.Value, f *internal.Field) bool {
+	for _, v := range values {
+		if _, empty := writeValue(v, f); !empty {
+			return false
+		}
+	}
+
+	return true
+}
+
+// UpdateStruct updates the row of the model, a struct or a pointer to a struct that implements
+// Tabler, found by its pk columns. Every other written column is set.
+func UpdateStruct(ctx context.Context, db DBTX, model interface{}) (sql.Result, error) {
+	table, v, err := writeModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateStruct(ctx, db, table, v)
+}
+
+// updateStruct updates the row of the struct value in the table.
+func updateStruct(ctx context.Context, db DBTX, table string, v reflect.Value) (sql.Result, error) {
+	mapping := internal.MappingOf(v.Type())
+
+	keys := keyColumns(mapping)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("rdbx: cannot update %s, it has no pk column", v.Type())
+	}
+
+	var set, where []string
+	var args []interface{}
+
+	for i := range mapping.Fields {
+		f := &mapping.Fields[i]
+		if !isInsertable(f) || f.Options.Has("pk") {
+			continue
+		}
+
+		value, empty := writeValue(v, f)
+		if empty && f.Options.Has("omitempty") {
+			continue
+		}
+
+		set = append(set, f.Column+" = ?")
+		args = append(args, value)
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("rdbx: %s has no column to update", v.Type())
+	}
+
+	for _, f := range keys {
+		value, _ := writeValue(v, f)
+		where = append(where, f.Column+" = ?")
+		args = append(args, value)
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(set, ", "), strings.Join(where, " AND "))
+
+	return db.ExecContext(ctx, query, args...)
+}
+
+// Upsert inserts the model, a struct or a pointer to a struct that implements Tabler, or updates
+// the row it conflicts with: ON DUPLICATE KEY UPDATE with MySQL, which conflicts on any unique key,
+// and ON CONFLICT on the pk columns with Postgres and SQLite. The inserted columns other than
+// the pk columns are updated. SQL Server is not supported.
+func Upsert(ctx context.Context, db DBTX, model interface{}) (sql.Result, error) {
+	table, v, err := writeModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := internal.MappingOf(v.Type())
+
+	columns, args := insertColumns(v, mapping)
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("rdbx: %s has no column to insert", v.Type())
+	}
+
+	var keys []string
+	for _, f := range keyColumns(mapping) {
+		keys = append(keys, f.Column)
+	}
+
+	var update []string
+	for _, c := range columns {
+		if f, _ := mapping.Field(c); !f.Options.Has("pk") {
+			update = append(update, c)
+		}
+	}
+
+	clause, err := dialectOf(db).upsertClause(keys, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ExecContext(ctx, insertQuery(table, columns, 1)+clause, args...)
+}
@@ -0,0 +1,174 @@
+package rdbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type writeUser struct {
+	ID        int64     `column:"id,pk,autoincrement"`
+	Name      string    `column:"name"`
+	Nickname  *string   `column:"nickname,omitempty"`
+	CreatedAt time.Time `column:"created_at,readonly"`
+}
+
+func (writeUser) TableName() string {
+	return "users"
+}
+
+func TestInsertStruct(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil)
+
+	mock.ExpectExec("INSERT INTO users (name) VALUES (?)").
+		WithArgs("alie").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+
+	u := writeUser{Name: "alie"}
+	_, err = InsertStruct(ctx, dbx, &u)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), u.ID)
+
+	nickname := "al"
+
+	mock.ExpectExec("INSERT INTO users (name, nickname) VALUES ($1, $2)").
+		WithArgs("alie", &nickname).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	u = writeUser{Name: "alie", Nickname: &nickname}
+	_, err = InsertStruct(ctx, NewDbx(db, nil, WithDialect(PostgresDialect)), &u)
+	assert.NoError(t, err)
+	assert.Zero(t, u.ID)
+
+	mock.ExpectExec("INSERT INTO logs (message) VALUES (?)").
+		WithArgs("hello").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	_, err = InsertStruct(ctx, dbx, &writeLog{Message: "hello"})
+	assert.NoError(t, err)
+
+	_, err = InsertStruct(ctx, dbx, struct {
+		ID int64 `column:"id"`
+	}{})
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertMany(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil, WithDialect(SQLServerDialect))
+
+	nickname := "al"
+
+	// SQL Server binds at most 2100 arguments: 1050 rows of two columns per statement.
+	users := make([]writeUser, 1051)
+	for i := range users {
+		users[i].Name = "alie"
+	}
+
+	users[1050].Nickname = &nickname
+
+	mock.ExpectExec(SQLServerDialect.Rebind(insertQuery("users", []string{"name", "nickname"}, 1050))).
+		WillReturnResult(sqlmock.NewResult(0, 1050))
+	mock.ExpectExec("INSERT INTO users (name, nickname) VALUES (@p1, @p2)").
+		WithArgs("alie", &nickname).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := InsertMany(ctx, dbx, users)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1051), n)
+
+	mock.ExpectExec("INSERT INTO users (name) VALUES (@p1), (@p2)").
+		WithArgs("alie", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err = InsertMany(ctx, dbx, []*writeUser{{Name: "alie"}, {Name: "bob"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = InsertMany(ctx, dbx, []writeUser(nil))
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateStruct(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	dbx := NewDbx(db, nil)
+
+	mock.ExpectExec("UPDATE users SET name = ? WHERE id = ?").
+		WithArgs("bob", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE settings SET value = ? WHERE key = ?").
+		WithArgs("dark", "").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = UpdateStruct(ctx, dbx, writeUser{ID: 1, Name: "bob"})
+	assert.NoError(t, err)
+
+	_, err = UpdateStruct(ctx, dbx, writeSetting{Value: "dark"})
+	assert.NoError(t, err)
+
+	_, err = UpdateStruct(ctx, dbx, writeLog{Message: "hello"})
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+
+	mock.ExpectExec("INSERT INTO settings (key, value) VALUES (?, ?) ON DUPLICATE KEY UPDATE value = VALUES(value)").
+		WithArgs("theme", "dark").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = Upsert(ctx, NewDbx(db, nil), writeSetting{Key: "theme", Value: "dark"})
+	assert.NoError(t, err)
+
+	mock.ExpectExec("INSERT INTO settings (key, value) VALUES ($1, $2) ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value").
+		WithArgs("theme", "dark").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = Upsert(ctx, NewDbx(db, nil, WithDialect(PostgresDialect)), writeSetting{Key: "theme", Value: "dark"})
+	assert.NoError(t, err)
+
+	_, err = Upsert(ctx, NewDbx(db, nil, WithDialect(SQLServerDialect)), writeSetting{Key: "theme", Value: "dark"})
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type writeSetting struct {
+	Key   string `column:"key,pk"`
+	Value string `column:"value"`
+}
+
+func (writeSetting) TableName() string {
+	return "settings"
+}
+
+type writeLog struct {
+	Message string `column:"message"`
+}
+
+func (*writeLog) TableName() string {
+	return "logs"
+}